@@ -1,16 +1,149 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	fsm_generator "fsm-framework/fsm-generator"
 )
 
+const usage = `usage: fsm-generator [command] [flags]
+
+commands:
+  generate  validate models, render previews and docs, generate code (default)
+  validate  validate models and migrations and explore their graphs without writing files,
+            exit code 1 on graph issues
+  preview   render model previews only
+  schema    print JSON Schema of model yaml
+  check     validate models, explore their graphs and compare generated code with models,
+            exit code 1 on issues or stale generated files
+
+flags:
+`
+
 // Генератор кода fsm моделей
 func main() {
-	// todo: парсинг параметров, help, пути до моделей и генерированных файлов
-	err := fsm_generator.Generate()
+	command, args := "generate", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	cfg := fsm_generator.DefaultConfig()
+
+	fs := flag.NewFlagSet("fsm-generator "+command, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+
+	var models, previewFormats string
+
+	fs.StringVar(&cfg.ModelsDir, "models", cfg.ModelsDir, "models yaml directory")
+	fs.StringVar(&cfg.MigrationsDir, "migrations", cfg.MigrationsDir, "tx migrations directory")
+	fs.StringVar(&cfg.PreviewsDir, "previews", cfg.PreviewsDir, "model previews directory")
+	fs.StringVar(&previewFormats, "preview-format", "",
+		"comma-separated preview formats: png, svg, dot, mermaid (png if graphviz is available, otherwise dot,mermaid)")
+	fs.StringVar(&cfg.DocsDir, "docs", cfg.DocsDir, "model markdown docs directory, empty to skip docs")
+	fs.StringVar(&cfg.OutputDir, "output", cfg.OutputDir, "generated model packages directory")
+	fs.StringVar(&cfg.Package, "package", cfg.Package, "generated go package name, {model} is replaced with model name")
+	fs.StringVar(&cfg.PackageDir, "package-dir", cfg.PackageDir,
+		"generated package directory in -output, {model} is replaced with model name (-package by default)")
+	fs.StringVar(&cfg.Framework, "framework", cfg.Framework,
+		"fsm-framework module import path for generated code (resolved from project go.mod by default)")
+	fs.StringVar(&models, "model", "", "comma-separated model names to process (all models by default)")
+
+	jsonOutput := fs.Bool("json", false, "machine-readable JSON report (validate, check)")
+
+	_ = fs.Parse(args) // ExitOnError
+
+	if models != "" {
+		cfg.Models = strings.Split(models, ",")
+	}
+
+	if previewFormats != "" {
+		var err error
+
+		cfg.PreviewFormats, err = fsm_generator.ParsePreviewFormats(previewFormats)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch command {
+	case "generate":
+		err := fsm_generator.Generate(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "validate":
+		validate(cfg, *jsonOutput)
+	case "preview":
+		err := fsm_generator.Preview(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "check":
+		check(cfg, *jsonOutput)
+	case "schema":
+		_, _ = os.Stdout.Write(fsm_generator.ModelSchema)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// validate проверяет модели и миграции и исследует графы моделей (Model.Check)
+func validate(cfg fsm_generator.Config, jsonOutput bool) {
+	models, err := fsm_generator.Validate(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	reports := make([]*fsm_generator.CheckReport, 0, len(models))
+	for _, model := range models {
+		reports = append(reports, model.Check())
+	}
+
+	report(reports, jsonOutput)
+}
+
+// check дополнительно к validate сравнивает сгенерированный код и документацию с моделями
+func check(cfg fsm_generator.Config, jsonOutput bool) {
+	reports, err := fsm_generator.CheckModels(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report(reports, jsonOutput)
+}
+
+// report выводит отчеты текстом или JSON, код выхода 1, если найдены проблемы
+func report(reports []*fsm_generator.CheckReport, jsonOutput bool) {
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		err := enc.Encode(reports)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ok := true
+
+	for _, r := range reports {
+		if !jsonOutput {
+			fmt.Print(r)
+		}
+
+		ok = ok && r.OK()
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
 }
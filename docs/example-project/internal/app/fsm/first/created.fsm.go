@@ -56,6 +56,18 @@ func (s *CreatedStateDeclaration) FallbackState() model.State {
 	return nil
 }
 
+func (s *CreatedStateDeclaration) RateLimit() *model.RateLimit {
+	return nil
+}
+
+func (s *CreatedStateDeclaration) Backoff() *model.Backoff {
+	return nil
+}
+
+func (s *CreatedStateDeclaration) CircuitBreaker() *model.CircuitBreaker {
+	return nil
+}
+
 func (s *CreatedStateDeclaration) IsInitial() bool {
 	return true
 }
@@ -48,6 +48,18 @@ func (s *DoneStateDeclaration) FallbackState() model.State {
 	return nil
 }
 
+func (s *DoneStateDeclaration) RateLimit() *model.RateLimit {
+	return nil
+}
+
+func (s *DoneStateDeclaration) Backoff() *model.Backoff {
+	return nil
+}
+
+func (s *DoneStateDeclaration) CircuitBreaker() *model.CircuitBreaker {
+	return nil
+}
+
 func (s *DoneStateDeclaration) IsInitial() bool {
 	return false
 }
@@ -48,6 +48,18 @@ func (s *ErrStateDeclaration) FallbackState() model.State {
 	return nil
 }
 
+func (s *ErrStateDeclaration) RateLimit() *model.RateLimit {
+	return nil
+}
+
+func (s *ErrStateDeclaration) Backoff() *model.Backoff {
+	return nil
+}
+
+func (s *ErrStateDeclaration) CircuitBreaker() *model.CircuitBreaker {
+	return nil
+}
+
 func (s *ErrStateDeclaration) IsInitial() bool {
 	return false
 }
@@ -17,6 +17,10 @@ func (m *FirstModel) Name() string {
 	return "first"
 }
 
+func (m *FirstModel) Version() string {
+	return "b1a7d2"
+}
+
 func (m *FirstModel) States() []model.State {
 	return []model.State{
 		CreatedState,
@@ -79,3 +83,7 @@ func (m *FirstModel) SetService(svc interface{}) error {
 func (m *FirstModel) Service() interface{} {
 	return m.svc
 }
+
+func (m *FirstModel) NewContextData() interface{} {
+	return nil
+}
@@ -56,6 +56,18 @@ func (s *SecondStateDeclaration) FallbackState() model.State {
 	return nil
 }
 
+func (s *SecondStateDeclaration) RateLimit() *model.RateLimit {
+	return nil
+}
+
+func (s *SecondStateDeclaration) Backoff() *model.Backoff {
+	return nil
+}
+
+func (s *SecondStateDeclaration) CircuitBreaker() *model.CircuitBreaker {
+	return nil
+}
+
 func (s *SecondStateDeclaration) IsInitial() bool {
 	return false
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 
 	callback_manager "fsm-framework/fsm-engine/callback-manager"
+	"fsm-framework/fsm-engine/clock"
 	"fsm-framework/fsm-engine/model"
 	"fsm-framework/fsm-engine/queue"
 	zlog "fsm-framework/misk/logger"
@@ -41,17 +42,32 @@ type HTTPCallbackManager struct {
 	b      queue.Broker
 	pushCh queue.Channel
 	pullCh queue.Channel
+	clock  clock.Clock
 }
 
-func New(ctx context.Context, r Repository, b queue.Broker) (*HTTPCallbackManager, error) {
+type Option func(c *HTTPCallbackManager)
+
+// WithClock источник времени для задержек повторов (по умолчанию системное время)
+func WithClock(clk clock.Clock) Option {
+	return func(c *HTTPCallbackManager) {
+		c.clock = clk
+	}
+}
+
+func New(ctx context.Context, r Repository, b queue.Broker, opts ...Option) (*HTTPCallbackManager, error) {
 	var err error
 
 	c := &HTTPCallbackManager{
 		c: http.Client{
 			Timeout: timeout,
 		},
-		r: r,
-		b: b,
+		r:     r,
+		b:     b,
+		clock: clock.Real{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
 
 	c.pushCh, err = c.b.Channel()
@@ -108,7 +124,7 @@ func (c *HTTPCallbackManager) Send(ctx context.Context, tx model.Tx) {
 
 		cbEv = cbEv.NewRetry()
 
-		time.Sleep(syncRetryDelay)
+		c.clock.Sleep(syncRetryDelay)
 	}
 
 	// if not succeeded add in db
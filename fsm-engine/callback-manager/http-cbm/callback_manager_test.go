@@ -0,0 +1,76 @@
+package http_cbm_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	http_cbm "fsm-framework/fsm-engine/callback-manager/http-cbm"
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/queue/memqueue"
+	"fsm-framework/fsm-engine/repository/memrepo"
+)
+
+type testTx struct {
+	model.TxRef
+	callbackURL string
+}
+
+func (t *testTx) CallbackURL() string {
+	return t.callbackURL
+}
+
+// Повторы обратного вызова идут по часам менеджера и не ждут реального времени
+func TestSendRetries(t *testing.T) {
+	ctx := context.Background()
+
+	var calls int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// все синхронные и первая асинхронная попытки неудачны
+		if atomic.AddInt32(&calls, 1) <= 4 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	repo := memrepo.New(func(tx model.Tx) model.Tx { return tx })
+	broker := memqueue.NewBroker()
+	defer broker.Close(ctx)
+
+	cbm, err := http_cbm.New(ctx, repo, broker, http_cbm.WithClock(clock.NewFake(time.Now())))
+	require.NoError(t, err)
+
+	defer cbm.Stop() // nolint: errcheck
+
+	tx := &testTx{TxRef: model.TxRef(uuid.New()), callbackURL: srv.URL}
+
+	cbm.Send(ctx, tx)
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) == 5 && broker.Idle()
+	}, 5*time.Second, time.Millisecond)
+
+	var sent int
+
+	for _, ev := range repo.CallbackEvents(tx.ID()) {
+		if ev.SentSuccessfully {
+			sent++
+
+			require.Equal(t, 5, ev.RetryN)
+		}
+	}
+
+	require.Equal(t, 1, sent)
+}
@@ -7,7 +7,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
@@ -69,7 +68,7 @@ func (c *HTTPCallbackManager) sendHTTP(ctx context.Context, cbEv *CallbackEvent)
 
 	span.LogFields(log.String("req", string(cbEv.RequestBody)))
 
-	cbEv.RequestTimestamp = time.Now()
+	cbEv.RequestTimestamp = c.clock.Now()
 
 	resp, err := c.c.Do(req)
 	if err != nil {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"fsm-framework/fsm-engine/queue"
 	zlog "fsm-framework/misk/logger"
@@ -54,7 +53,7 @@ func (c *HTTPCallbackManager) handler(ctx context.Context, d queue.Delivery) err
 	}
 
 	// задержка
-	time.Sleep(time.Until(cbEv.RequestTimestamp.Add(asyncRetryDelay)))
+	c.clock.Sleep(cbEv.RequestTimestamp.Add(asyncRetryDelay).Sub(c.clock.Now()))
 
 	// попытка отправить http запрос
 	cbEv, _ = c.sendHTTP(ctx, cbEv)
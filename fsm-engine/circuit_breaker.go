@@ -0,0 +1,135 @@
+package fsmengine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+// circuitBreakerProbeWait интервал ожидания результата пробного события другими событиями очереди
+const circuitBreakerProbeWait = time.Second
+
+type CircuitBreakerState string
+
+const (
+	// CircuitBreakerClosed обработка очереди идет в штатном режиме
+	CircuitBreakerClosed CircuitBreakerState = "closed"
+	// CircuitBreakerOpen обработка очереди приостановлена
+	CircuitBreakerOpen CircuitBreakerState = "open"
+	// CircuitBreakerHalfOpen обрабатывается пробное событие
+	CircuitBreakerHalfOpen CircuitBreakerState = "half_open"
+)
+
+// CircuitBreakerStatus состояние circuit breaker очереди состояния (в рамках текущей реплики)
+type CircuitBreakerStatus struct {
+	Queue string
+	State CircuitBreakerState
+	// Failures количество неудач обработчика подряд
+	Failures int
+	// OpenedAt время последней приостановки обработки (нулевое, если не приостанавливалась)
+	OpenedAt time.Time
+}
+
+// CircuitBreakerHook получает состояние circuit breaker очереди при каждом его изменении,
+// позволяет экспортировать его в метрики сервиса. Вызывается из обработчика очереди, не должен блокировать
+type CircuitBreakerHook func(ctx context.Context, status CircuitBreakerStatus)
+
+// circuitBreaker приостанавливает обработку очереди состояния при серии неудач обработчика
+type circuitBreaker struct {
+	m   sync.Mutex
+	cfg *model.CircuitBreaker
+
+	state    CircuitBreakerState
+	failures int
+	openedAt time.Time
+	// probing пробное событие уже обрабатывается
+	probing bool
+}
+
+func newCircuitBreaker(cfg *model.CircuitBreaker) *circuitBreaker {
+	return &circuitBreaker{
+		cfg:   cfg,
+		state: CircuitBreakerClosed,
+	}
+}
+
+// allow возвращает время, которое необходимо подождать перед обработкой события (0 – можно обрабатывать),
+// probe – событие является пробным и его результат определит возобновление обработки,
+// changed – состояние breaker изменилось (переход в half_open)
+func (cb *circuitBreaker) allow(now time.Time) (wait time.Duration, probe bool, changed bool) {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	switch cb.state {
+	case CircuitBreakerOpen:
+		wait = cb.openedAt.Add(cb.cfg.OpenTimeout).Sub(now)
+		if wait > 0 {
+			return wait, false, false
+		}
+
+		cb.state = CircuitBreakerHalfOpen
+		cb.probing = true
+
+		return 0, true, true
+	case CircuitBreakerHalfOpen:
+		if cb.probing {
+			return circuitBreakerProbeWait, false, false
+		}
+
+		cb.probing = true
+
+		return 0, true, false
+	default:
+		return 0, false, false
+	}
+}
+
+// record учитывает результат обработки события, возвращает true, если состояние breaker изменилось
+func (cb *circuitBreaker) record(now time.Time, failed bool, probe bool) bool {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	prev := cb.state
+
+	if probe {
+		cb.probing = false
+	}
+
+	if !failed {
+		cb.failures = 0
+		cb.state = CircuitBreakerClosed
+
+		return prev != cb.state
+	}
+
+	cb.failures++
+
+	if probe || cb.failures >= cb.cfg.FailureThreshold {
+		cb.state = CircuitBreakerOpen
+		cb.openedAt = now
+	}
+
+	return prev != cb.state
+}
+
+// cancelProbe освобождает пробу, если пробное событие не дошло до обработчика
+func (cb *circuitBreaker) cancelProbe() {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	cb.probing = false
+}
+
+func (cb *circuitBreaker) status(queueName string) CircuitBreakerStatus {
+	cb.m.Lock()
+	defer cb.m.Unlock()
+
+	return CircuitBreakerStatus{
+		Queue:    queueName,
+		State:    cb.state,
+		Failures: cb.failures,
+		OpenedAt: cb.openedAt,
+	}
+}
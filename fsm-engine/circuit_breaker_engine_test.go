@@ -0,0 +1,63 @@
+package fsmengine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	fsmengine "fsm-framework/fsm-engine"
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/model"
+)
+
+func TestCircuitBreakerPausesQueue(t *testing.T) {
+	mdl := newTestModel("breaker", "1")
+
+	created := mdl.state("CREATED", "SECOND")
+	created.initial = true
+	created.to("SECOND")
+
+	second := mdl.state("SECOND", "DONE")
+	second.breaker = &model.CircuitBreaker{FailureThreshold: 2, OpenTimeout: time.Minute}
+	second.to("DONE")
+
+	mdl.state("DONE").success = true
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+	h.FailHandler(second, 2)
+
+	tx := h.CreateTx(fsmtest.NewTx(), created)
+	h.RunUntilIdle()
+
+	h.AssertPath(tx, created, second, mdl.Resolve("DONE"))
+	h.AssertStatus(tx, model.TxStatusDone)
+
+	states := make([]fsmengine.CircuitBreakerState, 0, 3)
+	for _, status := range h.CircuitBreakerChanges() {
+		assert.Equal(t, second.Queue(), status.Queue)
+
+		states = append(states, status.State)
+	}
+
+	assert.Equal(t, []fsmengine.CircuitBreakerState{
+		fsmengine.CircuitBreakerOpen,
+		fsmengine.CircuitBreakerHalfOpen,
+		fsmengine.CircuitBreakerClosed,
+	}, states)
+
+	// пока breaker открыт, событие возвращается в очередь, а не удерживается обработчиком
+	assert.Positive(t, h.Broker.Stats(second.Queue()).Redelivered)
+
+	// отложенное событие не расходует попытки транзакции: после двух неудач следует пробное событие
+	var retries []int
+
+	for _, ev := range h.Repo.EventsOf(tx.ID()) {
+		if ev.StartState == second.Name() {
+			retries = append(retries, ev.RetryN)
+		}
+	}
+
+	assert.Equal(t, []int{0, 1, 2}, retries)
+	assert.Equal(t, fsmengine.CircuitBreakerClosed, h.Engine.CircuitBreakers()[0].State)
+}
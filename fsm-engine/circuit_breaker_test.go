@@ -0,0 +1,135 @@
+package fsmengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/model"
+)
+
+// breakerStep действие над circuit breaker и ожидаемый результат
+type breakerStep struct {
+	// advance сдвиг часов перед действием
+	advance time.Duration
+	// op allow, fail, success, cancel; fail и success учитывают результат пробного события, если probe
+	op    string
+	probe bool
+
+	wait    time.Duration
+	changed bool
+	state   CircuitBreakerState
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{
+			name: "opens after threshold and closes on probe success",
+			steps: []breakerStep{
+				{op: "fail", state: CircuitBreakerClosed},
+				{op: "fail", state: CircuitBreakerClosed},
+				{op: "allow", state: CircuitBreakerClosed},
+				{op: "fail", changed: true, state: CircuitBreakerOpen},
+				{op: "allow", wait: time.Minute, state: CircuitBreakerOpen},
+				{advance: 40 * time.Second, op: "allow", wait: 20 * time.Second, state: CircuitBreakerOpen},
+				{advance: 20 * time.Second, op: "allow", probe: true, changed: true, state: CircuitBreakerHalfOpen},
+				// пока пробное событие обрабатывается, остальные ожидают
+				{op: "allow", wait: circuitBreakerProbeWait, state: CircuitBreakerHalfOpen},
+				{op: "success", probe: true, changed: true, state: CircuitBreakerClosed},
+				{op: "allow", state: CircuitBreakerClosed},
+			},
+		},
+		{
+			name: "failed probe reopens",
+			steps: []breakerStep{
+				{op: "fail"},
+				{op: "fail"},
+				{op: "fail", changed: true, state: CircuitBreakerOpen},
+				{advance: time.Minute, op: "allow", probe: true, changed: true, state: CircuitBreakerHalfOpen},
+				{advance: 10 * time.Second, op: "fail", probe: true, changed: true, state: CircuitBreakerOpen},
+				// открыт заново с момента неудачи пробного события
+				{op: "allow", wait: time.Minute, state: CircuitBreakerOpen},
+			},
+		},
+		{
+			name: "success resets failures",
+			steps: []breakerStep{
+				{op: "fail"},
+				{op: "fail"},
+				{op: "success"},
+				{op: "fail"},
+				{op: "fail", state: CircuitBreakerClosed},
+				{op: "fail", changed: true, state: CircuitBreakerOpen},
+			},
+		},
+		{
+			name: "canceled probe is given to next event",
+			steps: []breakerStep{
+				{op: "fail"},
+				{op: "fail"},
+				{op: "fail", changed: true, state: CircuitBreakerOpen},
+				{advance: time.Minute, op: "allow", probe: true, changed: true, state: CircuitBreakerHalfOpen},
+				{op: "cancel", state: CircuitBreakerHalfOpen},
+				{op: "allow", probe: true, state: CircuitBreakerHalfOpen},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			cb := newCircuitBreaker(&model.CircuitBreaker{FailureThreshold: 3, OpenTimeout: time.Minute})
+
+			for i, step := range tt.steps {
+				clk.Advance(step.advance)
+
+				var (
+					wait    time.Duration
+					probe   bool
+					changed bool
+				)
+
+				switch step.op {
+				case "allow":
+					wait, probe, changed = cb.allow(clk.Now())
+				case "fail":
+					probe = step.probe
+					changed = cb.record(clk.Now(), true, step.probe)
+				case "success":
+					probe = step.probe
+					changed = cb.record(clk.Now(), false, step.probe)
+				case "cancel":
+					cb.cancelProbe()
+				}
+
+				assert.Equal(t, step.wait, wait, "step %d wait", i)
+				assert.Equal(t, step.probe, probe, "step %d probe", i)
+				assert.Equal(t, step.changed, changed, "step %d changed", i)
+
+				if step.state != "" {
+					assert.Equal(t, step.state, cb.status("queue").State, "step %d state", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerStatus(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(&model.CircuitBreaker{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	cb.record(now, true, false)
+	cb.record(now, true, false)
+
+	assert.Equal(t, CircuitBreakerStatus{
+		Queue:    "queue",
+		State:    CircuitBreakerOpen,
+		Failures: 2,
+		OpenedAt: now,
+	}, cb.status("queue"))
+}
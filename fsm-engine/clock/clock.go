@@ -0,0 +1,155 @@
+package clock
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Clock источник времени движка, позволяет тестам управлять задержками обработки
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	Sleep(d time.Duration)
+	// AfterFunc вызывает f спустя d
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer отложенный вызов AfterFunc
+type Timer interface {
+	// Stop отменяет вызов, false – если он уже выполнен или отменен
+	Stop() bool
+}
+
+var (
+	_ Clock = Real{}
+	_ Clock = &Fake{}
+)
+
+// Real системное время
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Fake управляемое время для тестов: Sleep не блокирует, а сдвигает текущее время на d.
+// Отложенные вызовы AfterFunc выполняются в Advance (и Sleep), когда их время наступило
+type Fake struct {
+	m      sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *Fake
+	at    time.Time
+	f     func()
+}
+
+// Stop удаляет вызов из ожидающих
+func (t *fakeTimer) Stop() bool {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	f.Advance(d)
+
+	// даем поработать остальным горутинам, как при настоящем ожидании
+	runtime.Gosched()
+}
+
+// AfterFunc откладывает вызов до момента, когда часы будут сдвинуты на d (при d <= 0 – до ближайшего Advance)
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	t := &fakeTimer{clock: f, at: f.now.Add(d), f: fn}
+	f.timers = append(f.timers, t)
+
+	return t
+}
+
+// Advance сдвигает текущее время на d и выполняет наступившие вызовы AfterFunc в порядке их времени
+func (f *Fake) Advance(d time.Duration) {
+	if d > 0 {
+		f.m.Lock()
+		f.now = f.now.Add(d)
+		f.m.Unlock()
+	}
+
+	for {
+		t := f.nextDue()
+		if t == nil {
+			return
+		}
+
+		// вызов выполняется без f.m: он может обращаться к часам и откладывать новые вызовы
+		t.f()
+	}
+}
+
+// nextDue забирает самый ранний наступивший вызов, nil – если таких нет
+func (f *Fake) nextDue() *fakeTimer {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	next := -1
+
+	for i, t := range f.timers {
+		if t.at.After(f.now) {
+			continue
+		}
+
+		if next < 0 || t.at.Before(f.timers[next].at) {
+			next = i
+		}
+	}
+
+	if next < 0 {
+		return nil
+	}
+
+	t := f.timers[next]
+	f.timers = append(f.timers[:next], f.timers[next+1:]...)
+
+	return t
+}
@@ -0,0 +1,129 @@
+package fsmengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+
+	"fsm-framework/fsm-engine/model"
+	zlog "fsm-framework/misk/logger"
+)
+
+// errNoEventRepository репозиторий не хранит историю событий, необходимую для компенсации
+var errNoEventRepository = errors.New("repository doesn't implement model.EventRepository, compensation is not supported")
+
+// compensate исполняет компенсирующий обработчик состояния, указанного в событии (saga)
+func (p *processPipeline) compensate(ctx context.Context) {
+	sp, handlerCtx := opentracing.StartSpanFromContext(ctx, "Compensation Handler", opentracing.Tag{
+		Key:   "compensated_state",
+		Value: p.event.CompensatedState,
+	})
+	defer sp.Finish()
+
+	state := p.state.Model().Resolve(p.event.CompensatedState)
+	if state == nil {
+		p.compensationErr = fmt.Errorf("compensated state %s not found in model", p.event.CompensatedState)
+	} else if compensator, ok := state.(model.Compensator); !ok {
+		p.compensationErr = fmt.Errorf("state %s has no compensation handler", p.event.CompensatedState)
+	} else {
+		p.compensationErr = compensator.Compensate(handlerCtx, p.event)
+	}
+
+	if p.compensationErr != nil {
+		zlog.Ctx(ctx).Error().Err(p.compensationErr).
+			Str("compensated_state", p.event.CompensatedState).
+			Msg("compensation failed")
+		p.span.SetTag("error", true).LogFields(log.Message("compensation failed"), log.Error(p.compensationErr))
+	}
+}
+
+// resolveNextCompensation определяет следующее событие цепочки компенсаций:
+// повтор текущей компенсации при ошибке, либо компенсация следующего состояния
+func (p *processPipeline) resolveNextCompensation() {
+	if p.isPanicRecovered || p.compensationErr != nil {
+		p.event.Status = model.EventStatusRetry
+		p.nextState = p.state
+		p.nextCompensations = append([]string{p.event.CompensatedState}, p.event.Compensations...)
+
+		return
+	}
+
+	p.event.Status = model.EventStatusDone
+
+	if len(p.event.Compensations) == 0 {
+		p.nextState = nil
+
+		return
+	}
+
+	p.nextState = p.state
+	p.nextCompensations = p.event.Compensations
+}
+
+// planCompensations по истории событий определяет пройденные транзакцией состояния,
+// для которых задан компенсирующий обработчик, в обратном порядке (каждое состояние один раз)
+func (p *processPipeline) planCompensations(ctx context.Context) error {
+	// модели с компенсациями не добавляются в движок без model.EventRepository (Engine.AddModel)
+	events, err := []*model.Event(nil), errNoEventRepository
+	if repo, ok := p.cfg.repo.(model.EventRepository); ok {
+		events, err = repo.Events(ctx, p.event.Tx.ID())
+	}
+
+	if err != nil {
+		p.span.SetTag("error", true).
+			LogFields(log.Message("can't get tx events for compensation"), log.Error(err))
+		zlog.Ctx(ctx).Error().Err(err).Msg("can't get tx events for compensation")
+
+		p.delivery.Reject(ctx)
+
+		return fmt.Errorf("tx events for compensation: %w", err)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Created.Before(events[j].Created)
+	})
+
+	var plan []string
+
+	// состояние, пройденное несколько раз (цикл, ручной переход), компенсируется один раз
+	// в порядке последнего прохождения
+	planned := make(map[string]bool, len(events))
+
+	for i := len(events) - 1; i >= 0; i-- {
+		ev := events[i]
+
+		// компенсируем только успешно обработанные события состояний
+		if ev.Status != model.EventStatusDone || ev.IsCompensation() || ev.StartState == p.state.Name() ||
+			planned[ev.StartState] {
+			continue
+		}
+
+		state := p.state.Model().Resolve(ev.StartState)
+		if state == nil {
+			continue
+		}
+
+		if _, ok := state.(model.Compensator); !ok {
+			continue
+		}
+
+		planned[ev.StartState] = true
+		plan = append(plan, state.Name())
+	}
+
+	if len(plan) == 0 {
+		return nil
+	}
+
+	p.span.LogFields(log.Object("compensations", plan))
+	zlog.Ctx(ctx).Info().Strs("compensations", plan).Msg("tx compensation started")
+
+	p.nextState = p.state
+	p.nextCompensations = plan
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package fsmengine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fsmengine "fsm-framework/fsm-engine"
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/lock/maplock"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/queue/memqueue"
+	"fsm-framework/fsm-engine/repository/memrepo"
+)
+
+// sagaModel CREATED -> RESERVE -> CHARGE -> FAILED, компенсации RESERVE и CHARGE пишутся в журнал
+func sagaModel(calls *recorder, charge func(ctx context.Context, ev *model.Event) error) *testModel {
+	m := newTestModel("saga", "1")
+
+	created := m.state("CREATED", "RESERVE")
+	created.initial = true
+	created.to("RESERVE")
+
+	m.compensated("RESERVE", func(ctx context.Context, ev *model.Event) error {
+		calls.add("RESERVE")
+
+		return nil
+	}, "CHARGE").to("CHARGE")
+
+	m.compensated("CHARGE", func(ctx context.Context, ev *model.Event) error {
+		calls.add("CHARGE")
+
+		return charge(ctx, ev)
+	}, "FAILED").to("FAILED")
+
+	m.state("FAILED").fail = true
+
+	return m
+}
+
+func TestCompensationReverseOrder(t *testing.T) {
+	calls := &recorder{}
+	mdl := sagaModel(calls, func(ctx context.Context, ev *model.Event) error {
+		return nil
+	})
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+
+	tx := h.CreateTx(fsmtest.NewTx(), mdl.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	h.AssertPath(tx, mdl.Resolve("CREATED"), mdl.Resolve("RESERVE"), mdl.Resolve("CHARGE"), mdl.Resolve("FAILED"))
+	h.AssertStatus(tx, model.TxStatusDone)
+	assert.Equal(t, []string{"CHARGE", "RESERVE"}, calls.list())
+
+	// уведомление отправляется один раз, после всех компенсаций
+	assert.Len(t, h.Callbacks(), 1)
+}
+
+func TestCompensationPanicRetried(t *testing.T) {
+	calls := &recorder{}
+	panicked := false
+	mdl := sagaModel(calls, func(ctx context.Context, ev *model.Event) error {
+		if !panicked {
+			panicked = true
+
+			panic("charge compensation failure")
+		}
+
+		return nil
+	})
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+
+	tx := h.CreateTx(fsmtest.NewTx(), mdl.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	// компенсация повторяется после паники, цепочка продолжается
+	assert.Equal(t, []string{"CHARGE", "CHARGE", "RESERVE"}, calls.list())
+	h.AssertStatus(tx, model.TxStatusDone)
+	assert.Len(t, h.Callbacks(), 1)
+}
+
+func TestCompensationPanicExhausted(t *testing.T) {
+	calls := &recorder{}
+	mdl := sagaModel(calls, func(ctx context.Context, ev *model.Event) error {
+		panic("charge compensation failure")
+	})
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+
+	tx := h.CreateTx(fsmtest.NewTx(), mdl.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	// исчерпав попытки, транзакция остается в неудачном состоянии с ошибкой, RESERVE не компенсируется
+	want := make([]string, model.EventRetryMaxCount-1)
+	for i := range want {
+		want[i] = "CHARGE"
+	}
+
+	assert.Equal(t, want, calls.list())
+	h.AssertStatus(tx, model.TxStatusError)
+	assert.Equal(t, "FAILED", h.Tx(tx).State().Name())
+}
+
+func TestCompensationRepeatedState(t *testing.T) {
+	calls := &recorder{}
+	m := newTestModel("saga_loop", "1")
+
+	created := m.state("CREATED", "RESERVE").to("RESERVE")
+	created.initial = true
+
+	// CREATED -> RESERVE -> CHARGE -> RESERVE -> FAILED
+	visits := 0
+	reserve := m.compensated("RESERVE", func(ctx context.Context, ev *model.Event) error {
+		calls.add("RESERVE")
+
+		return nil
+	}, "CHARGE", "FAILED")
+	reserve.handler = func(ctx context.Context, ev *model.Event) model.State {
+		visits++
+		if visits == 1 {
+			return m.Resolve("CHARGE")
+		}
+
+		return m.Resolve("FAILED")
+	}
+
+	m.compensated("CHARGE", func(ctx context.Context, ev *model.Event) error {
+		calls.add("CHARGE")
+
+		return nil
+	}, "RESERVE").to("RESERVE")
+
+	m.state("FAILED").fail = true
+
+	h := fsmtest.New(t, fsmtest.CloneTx, m)
+
+	tx := h.CreateTx(fsmtest.NewTx(), m.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	h.AssertPath(tx, m.Resolve("CREATED"), m.Resolve("RESERVE"), m.Resolve("CHARGE"), m.Resolve("RESERVE"),
+		m.Resolve("FAILED"))
+	h.AssertStatus(tx, model.TxStatusDone)
+
+	// RESERVE компенсируется один раз, в порядке последнего прохождения
+	assert.Equal(t, []string{"RESERVE", "CHARGE"}, calls.list())
+}
+
+// basicEngine движок над репозиторием, реализующим только model.Repository (без опциональных интерфейсов)
+func basicEngine(t *testing.T) *fsmengine.Engine {
+	t.Helper()
+
+	locker, err := maplock.NewLocker()
+	require.NoError(t, err)
+
+	engine, err := fsmengine.New(fsmengine.Config{
+		Repository:      struct{ model.Repository }{memrepo.New(fsmtest.CloneTx)},
+		Locker:          locker,
+		Broker:          memqueue.NewBroker(),
+		CallbackManager: nopCallbacks{},
+	})
+	require.NoError(t, err)
+
+	return engine
+}
+
+func TestCompensationRequiresEventRepository(t *testing.T) {
+	err := basicEngine(t).AddModel(context.Background(), sagaModel(&recorder{}, nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model.EventRepository")
+}
@@ -0,0 +1,129 @@
+package fsmengine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/model"
+)
+
+type testContextData struct {
+	RefID string `json:"ref_id"`
+	Steps int    `json:"steps"`
+}
+
+// contextDataModel CREATED -> SECOND -> DONE, обработчики пишут в журнал увиденные контекстные данные
+func contextDataModel(calls *recorder) *testModel {
+	m := newTestModel("context", "1")
+	m.newData = func() interface{} {
+		return &testContextData{}
+	}
+
+	created := m.state("CREATED", "SECOND")
+	created.initial = true
+	created.handler = func(ctx context.Context, ev *model.Event) model.State {
+		data := ev.Data.(*testContextData)
+		data.RefID = "ref-1"
+		data.Steps++
+
+		return m.Resolve("SECOND")
+	}
+
+	second := m.state("SECOND", "DONE")
+	second.handler = func(ctx context.Context, ev *model.Event) model.State {
+		data := ev.Data.(*testContextData)
+		calls.add(data.RefID)
+		data.Steps++
+
+		return m.Resolve("DONE")
+	}
+
+	m.state("DONE").success = true
+
+	return m
+}
+
+func storedContextData(t *testing.T, tx model.Tx) *testContextData {
+	t.Helper()
+
+	data := &testContextData{}
+
+	version, err := model.DecodeContextData(tx.ContextData(), data)
+	require.NoError(t, err)
+	assert.Equal(t, "1", version)
+
+	return data
+}
+
+func TestContextDataPassedBetweenStates(t *testing.T) {
+	calls := &recorder{}
+	mdl := contextDataModel(calls)
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+
+	tx := h.CreateTx(fsmtest.NewTx(), mdl.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	h.AssertPath(tx, mdl.Resolve("CREATED"), mdl.Resolve("SECOND"), mdl.Resolve("DONE"))
+	assert.Equal(t, []string{"ref-1"}, calls.list())
+	assert.Equal(t, &testContextData{RefID: "ref-1", Steps: 2}, storedContextData(t, h.Tx(tx)))
+}
+
+func TestContextDataNotStoredAfterPanic(t *testing.T) {
+	calls := &recorder{}
+	mdl := contextDataModel(calls)
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+
+	// первый вызов SECOND меняет данные и падает
+	panicked := false
+	h.StubHandler(mdl.Resolve("SECOND"), func(ctx context.Context, ev *model.Event) model.State {
+		data := ev.Data.(*testContextData)
+		calls.add(data.RefID)
+
+		if !panicked {
+			panicked = true
+			data.RefID = "dirty"
+			data.Steps = 100
+
+			panic("second failure")
+		}
+
+		data.Steps++
+
+		return mdl.Resolve("DONE")
+	})
+
+	tx := h.CreateTx(fsmtest.NewTx(), mdl.Resolve("CREATED"), model.WithContextData(&testContextData{Steps: 10}))
+	h.RunUntilIdle()
+
+	// повтор видит данные предыдущего состояния, а не частично измененные упавшим обработчиком
+	assert.Equal(t, []string{"ref-1", "ref-1"}, calls.list())
+	assert.Equal(t, &testContextData{RefID: "ref-1", Steps: 12}, storedContextData(t, h.Tx(tx)))
+	h.AssertStatus(tx, model.TxStatusDone)
+}
+
+func TestContextDataUndecodable(t *testing.T) {
+	calls := &recorder{}
+	mdl := contextDataModel(calls)
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+
+	created := fsmtest.NewTx()
+	created.SetContextData([]byte(`{"version":"1","data":"not an object"}`))
+
+	tx := h.CreateTx(created, mdl.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	// событие не теряется молча: транзакция остается в состоянии со статусом ошибки
+	h.AssertPath(tx, mdl.Resolve("CREATED"))
+	h.AssertStatus(tx, model.TxStatusError)
+
+	events := h.Repo.EventsOf(tx.ID())
+	require.Len(t, events, 1)
+	assert.Equal(t, model.EventStatusError, events[0].Status)
+}
@@ -2,8 +2,11 @@ package fsmengine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/log"
@@ -14,26 +17,51 @@ import (
 	zlog "github.com/rs/zerolog"
 
 	callback_manager "fsm-framework/fsm-engine/callback-manager"
+	"fsm-framework/fsm-engine/clock"
 	"fsm-framework/fsm-engine/lock"
 	"fsm-framework/fsm-engine/model"
 	"fsm-framework/fsm-engine/queue"
+	"fsm-framework/fsm-engine/ratelimit"
 )
 
+// defaultIdempotencyKeyRetention время хранения ключей идемпотентности по умолчанию
+const defaultIdempotencyKeyRetention = 24 * time.Hour
+
 // Engine Машина состояний отвечает за переход транзакции между состояниями, работу с очередью и локами
 type Engine struct {
 	// broker система управления очередями сообщений
 	broker queue.Broker
 	// locker инструмент для взятия эксклюзивных блокировок обработки транзакций
 	locker lock.Locker
+	// limiter распределенное ограничение частоты обработки состояний (rate_limit)
+	limiter ratelimit.Limiter
+	// transitions публикация записей о переходах транзакций (может быть nil)
+	transitions *TransitionPublisher
+	// transitionRepo outbox записей о переходах, тот же репозиторий, что и repo (nil, если публикация не настроена)
+	transitionRepo model.TransitionRepository
 	// repo репозиторий со всеми, необходимыми в ходе процессинга события, методами
 	repo model.Repository
 	// verboseTracing подробное логгирование в трейсинг
 	verboseTracing bool
+	// idempotencyKeyRetention время хранения ключей идемпотентности создания транзакций
+	idempotencyKeyRetention time.Duration
 	// cm управление отправкой обратного вызова (sync/async)
 	cm callback_manager.CallbackManager
-
+	// clock источник времени движка
+	clock clock.Clock
+	// interceptor обертка вызова обработчиков состояний (может быть nil)
+	interceptor HandlerInterceptor
+	// breakerHook получает изменения состояний circuit breaker (может быть nil)
+	breakerHook CircuitBreakerHook
+
+	// m защищает список моделей и обработчиков состояний при выводе версий из эксплуатации
+	m sync.RWMutex
+	// models инициализированные модели (в порядке добавления, последняя версия модели – актуальная)
 	models []model.Model
+	// states обработчики по состояниям всех версий моделей
 	states map[model.State]*StateProcessor
+	// queues обработчики по очередям, очередь состояния общая для всех версий модели
+	queues map[string]*StateProcessor
 }
 
 type Config struct {
@@ -41,38 +69,91 @@ type Config struct {
 	Locker          lock.Locker
 	Broker          queue.Broker
 	CallbackManager callback_manager.CallbackManager
-	VerboseTracing  bool
+	// Limiter ограничение частоты обработки состояний, обязателен, если в моделях задан rate_limit
+	Limiter        ratelimit.Limiter
+	VerboseTracing bool
+	// Transitions публикация записей о зафиксированных переходах транзакций (CDC), опционально.
+	// Repository должен реализовывать model.TransitionRepository
+	Transitions *TransitionPublisher
+	// IdempotencyKeyRetention время хранения ключей идемпотентности (по умолчанию сутки),
+	// ключи поддерживаются, если Repository реализует model.IdempotencyRepository
+	IdempotencyKeyRetention time.Duration
+	// Clock источник времени движка: создание событий, задержки повторов и блокировок (по умолчанию системное время)
+	Clock clock.Clock
+	// HandlerInterceptor обертка вызова обработчиков состояний (тесты, метрики), опционально
+	HandlerInterceptor HandlerInterceptor
+	// CircuitBreakerHook получает изменения состояний circuit breaker очередей (метрики), опционально
+	CircuitBreakerHook CircuitBreakerHook
 }
 
+// HandlerFunc обработчик события состояния, возвращает следующее состояние
+type HandlerFunc func(ctx context.Context, ev *model.Event) model.State
+
+// HandlerInterceptor оборачивает вызов обработчика состояния, handler – исходный обработчик
+type HandlerInterceptor func(ctx context.Context, state model.State, ev *model.Event, handler HandlerFunc) model.State
+
 // New создает машину состояний
-func New(cfg Config) *Engine {
+func New(cfg Config) (*Engine, error) {
 	fsm := &Engine{
-		broker:         cfg.Broker,
-		locker:         cfg.Locker,
-		repo:           cfg.Repository,
-		cm:             cfg.CallbackManager,
-		verboseTracing: cfg.VerboseTracing,
+		broker:                  cfg.Broker,
+		locker:                  cfg.Locker,
+		limiter:                 cfg.Limiter,
+		transitions:             cfg.Transitions,
+		repo:                    cfg.Repository,
+		cm:                      cfg.CallbackManager,
+		verboseTracing:          cfg.VerboseTracing,
+		idempotencyKeyRetention: cfg.IdempotencyKeyRetention,
+		clock:                   cfg.Clock,
+		interceptor:             cfg.HandlerInterceptor,
+		breakerHook:             cfg.CircuitBreakerHook,
+	}
+
+	if fsm.idempotencyKeyRetention == 0 {
+		fsm.idempotencyKeyRetention = defaultIdempotencyKeyRetention
+	}
+
+	if fsm.clock == nil {
+		fsm.clock = clock.Real{}
 	}
 
 	fsm.states = make(map[model.State]*StateProcessor, 128)
+	fsm.queues = make(map[string]*StateProcessor, 128)
+
+	// записи о переходах сохраняются в том же репозитории, что и транзакции, иначе атомарность не гарантируется
+	if fsm.transitions != nil {
+		repo, ok := cfg.Repository.(model.TransitionRepository)
+		if !ok {
+			return nil, errors.New("transitions publishing requires repository implementing model.TransitionRepository")
+		}
+
+		err := fsm.transitions.start(repo)
+		if err != nil {
+			return nil, err
+		}
 
-	return fsm
+		fsm.transitionRepo = repo
+	}
+
+	return fsm, nil
 }
 
 // Stop закрывает все соединения, останавливая обработку событий
 func (e *Engine) Stop(ctx context.Context) {
 	var err error
 
+	e.m.RLock()
+	defer e.m.RUnlock()
+
 	// сначала останавливаем консюминг всех очередей (паралелльно)
 	wg := sync.WaitGroup{}
 
-	for _, stateProcessor := range e.states {
+	for _, stateProcessor := range e.queues {
 		wg.Add(1)
 
 		go func(stateProcessor *StateProcessor) {
 			defer wg.Done()
 
-			closeErr := stateProcessor.consumerCh.Close()
+			closeErr := stateProcessor.StopConsume()
 			if closeErr != nil {
 				zlog.Ctx(ctx).Error().Err(err).Msg("error while stopping consumer in fsm")
 			}
@@ -93,6 +174,13 @@ func (e *Engine) Stop(ctx context.Context) {
 
 	wg.Wait()
 
+	if e.transitions != nil {
+		err = e.transitions.Close()
+		if err != nil {
+			zlog.Ctx(ctx).Error().Err(err).Msg("error while closing transition publisher in fsm")
+		}
+	}
+
 	// потом останавливаем работу с брокером полностью
 	e.broker.Close(ctx)
 
@@ -101,20 +189,48 @@ func (e *Engine) Stop(ctx context.Context) {
 		zlog.Ctx(ctx).Error().Err(err).Msg("error while closing broker in fsm")
 	}
 
+	if e.limiter != nil {
+		err = e.limiter.Close()
+		if err != nil {
+			zlog.Ctx(ctx).Error().Err(err).Msg("error while closing limiter in fsm")
+		}
+	}
+
 	zlog.Ctx(ctx).Info().Msg("fsm stopped")
 }
 
-// AddModel инициализирует очередную fsm модель
+// AddModel инициализирует очередную fsm модель.
+// Версии одной модели следует добавлять от старых к новым: последняя добавленная версия считается актуальной
 func (e *Engine) AddModel(ctx context.Context, newModel model.Model) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+
 	// проверяем, нет ли такой модели в списке инициализации
 	for _, mdl := range e.models {
 		if newModel == mdl {
 			return fmt.Errorf("fsm-model has initialized already")
 		}
+
+		if newModel.Name() == mdl.Name() && newModel.Version() == mdl.Version() {
+			return fmt.Errorf("fsm-model %s v.%s has initialized already", mdl.Name(), mdl.Version())
+		}
+	}
+
+	err := e.validateStates(newModel)
+	if err != nil {
+		return err
 	}
 
 	// инициализируем все состояния переданной модели
 	for _, s := range newModel.States() {
+		// очередь состояния уже обрабатывается другой версией модели
+		if sp, ok := e.queues[s.Queue()]; ok {
+			sp.addState(s)
+			e.states[s] = sp
+
+			continue
+		}
+
 		publisherCh, err := e.broker.Channel()
 		if err != nil {
 			return fmt.Errorf("state channel creation error: %w", err)
@@ -123,9 +239,15 @@ func (e *Engine) AddModel(ctx context.Context, newModel model.Model) error {
 		cfg := &pipelineConfig{
 			ch:             publisherCh,
 			locker:         e.locker,
+			limiter:        e.limiter,
+			transitions:    e.transitions,
+			transitionRepo: e.transitionRepo,
 			repo:           e.repo,
 			verboseTracing: e.verboseTracing,
 			cm:             e.cm,
+			clock:          e.clock,
+			interceptor:    e.interceptor,
+			breakerHook:    e.breakerHook,
 		}
 
 		// consume
@@ -134,13 +256,11 @@ func (e *Engine) AddModel(ctx context.Context, newModel model.Model) error {
 			return err
 		}
 
-		sp := &StateProcessor{
-			state:       s,
-			consumerCh:  consumerCh,
-			publisherCh: publisherCh,
-		}
+		sp := newStateProcessor(s.Queue(), e.broker, consumerCh, publisherCh)
+		sp.addState(s)
 
 		e.states[s] = sp
+		e.queues[s.Queue()] = sp
 
 		err = sp.StartConsume(ctx, cfg)
 		if err != nil {
@@ -152,22 +272,44 @@ func (e *Engine) AddModel(ctx context.Context, newModel model.Model) error {
 
 	e.models = append(e.models, newModel)
 
-	zlog.Ctx(ctx).Info().Str("model", newModel.Name()).Msg("fsm consumers started")
+	zlog.Ctx(ctx).Info().
+		Str("model", newModel.Name()).
+		Str("model_version", newModel.Version()).
+		Msg("fsm consumers started")
 
 	return nil
 }
 
-// Resolve ищем состояние по названию среди инициализированных моделей, либо nil
+// Resolve ищем состояние по названию среди последних версий инициализированных моделей, либо nil
 func (e *Engine) Resolve(ctx context.Context, state string) (model.State, model.Model) {
+	return e.ResolveVersion(ctx, state, "")
+}
+
+// ResolveVersion ищем состояние по названию в указанной версии модели, либо nil.
+// Если версия не указана, поиск ведется среди последних версий моделей
+func (e *Engine) ResolveVersion(ctx context.Context, state string, version string) (model.State, model.Model) {
 	span, _ := opentracing.StartSpanFromContext(ctx, "resolving state", opentracing.Tag{
 		Key:   "state",
 		Value: state,
+	}, opentracing.Tag{
+		Key:   "model_version",
+		Value: version,
 	})
 	defer span.Finish()
 
-	for _, mdl := range e.models {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	// обходим с конца, чтобы первой найти последнюю версию модели
+	for i := len(e.models) - 1; i >= 0; i-- {
+		mdl := e.models[i]
+
+		if version != "" && mdl.Version() != version {
+			continue
+		}
+
 		if found := mdl.Resolve(state); found != nil {
-			span.LogFields(log.String("model", mdl.Name()))
+			span.LogFields(log.String("model", mdl.Name()), log.String("model_version", mdl.Version()))
 
 			return found, mdl
 		}
@@ -178,16 +320,23 @@ func (e *Engine) Resolve(ctx context.Context, state string) (model.State, model.
 	return nil, nil
 }
 
-// CreateTx задает транзакции начальное состояние и отправляет событие на его обработку
-func (e *Engine) CreateTx(ctx context.Context, tx model.Tx, initState model.State) error {
+// CreateTx задает транзакции начальное состояние и отправляет событие на его обработку,
+// возвращает сохраненную транзакцию (ранее созданную, если повторно передан ключ идемпотентности)
+func (e *Engine) CreateTx(ctx context.Context, tx model.Tx, initState model.State,
+	opts ...model.CreateTxOption) (model.Tx, error) {
+	options := &model.CreateTxOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	// транзакция должна быть передана
 	if tx == nil {
-		return status.Error(codes.NotFound, "trying to move empty tx")
+		return nil, status.Error(codes.NotFound, "trying to move empty tx")
 	}
 
 	// нельзя создаться в пустое состояние
 	if initState == nil {
-		return status.Error(codes.Internal, "trying to move in nil state")
+		return nil, status.Error(codes.Internal, "trying to move in nil state")
 	}
 
 	createTxSpan, ctx := opentracing.StartSpanFromContext(ctx, "creating tx", opentracing.Tag{
@@ -199,32 +348,46 @@ func (e *Engine) CreateTx(ctx context.Context, tx model.Tx, initState model.Stat
 	})
 	defer createTxSpan.Finish()
 
-	// определяем модель состояния и инициализирована ли она
-	_, initModel := e.Resolve(ctx, initState.Name())
+	// определяем модель состояния и инициализирована ли она,
+	// новые транзакции всегда создаются в последней версии модели
+	latestInitState, initModel := e.Resolve(ctx, initState.Name())
 	if initModel == nil {
 		createTxSpan.LogFields(log.Message("state has no model"))
 
-		return status.Errorf(codes.Internal, "%s has no model", initState.Name())
+		return nil, status.Errorf(codes.Internal, "%s has no model", initState.Name())
 	}
 
+	initState = latestInitState
+
 	// проверяем можем ли сделать
 	if !initState.IsInitial() {
 		createTxSpan.LogFields(log.Message("state isn't initial state"))
 
-		return status.Errorf(codes.PermissionDenied, "state isn't initial state. state: %s", initState.Name())
+		return nil, status.Errorf(codes.PermissionDenied, "state isn't initial state. state: %s", initState.Name())
 	}
 
 	// обработчик нового состояния
-	initStateProcessor, ok := e.states[initState]
+	initStateProcessor, ok := e.processor(initState)
 	if !ok {
 		createTxSpan.LogFields(log.Message("state not initialized"))
 
-		return status.Errorf(codes.Internal, "%s not initialized", initState.Name())
+		return nil, status.Errorf(codes.Internal, "%s not initialized", initState.Name())
 	}
 
 	// устанавливаем параметры транзакции, отвечающие за состояние
 	tx.SetState(initState)
 	tx.SetStatus(model.TxStatusPending)
+	tx.SetModelVersion(initModel.Version())
+
+	// начальные контекстные данные модели
+	if options.ContextData != nil {
+		data, err := model.EncodeContextData(initModel.Version(), options.ContextData)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "context data error: %s", err.Error())
+		}
+
+		tx.SetContextData(data)
+	}
 
 	// создаем корневой span всего процессинга
 	var parentTraceID, parentSpanID string
@@ -244,18 +407,33 @@ func (e *Engine) CreateTx(ctx context.Context, tx model.Tx, initState model.Stat
 	tx.SetSpanID(parentSpanID)
 
 	// сохраняем сведения в БД
-	err := e.repo.CreateTransaction(ctx, tx)
-	if err != nil {
-		return status.Errorf(codes.Internal, "create transaction error: %s", err.Error())
+	if options.IdempotencyKey != "" {
+		stored, created, err := e.createTxIdempotent(ctx, tx, options.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+
+		// транзакция уже была создана ранее по этому ключу, событие повторно не отправляем
+		if !created {
+			createTxSpan.LogFields(log.Message("tx already created with idempotency key"),
+				log.String("stored_tx_id", stored.ID().String()))
+
+			return stored, nil
+		}
+	} else {
+		err := e.repo.CreateTransaction(ctx, tx)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "create transaction error: %s", err.Error())
+		}
 	}
 
 	// создаем событие для обработки
-	ev := model.NewEvent(initState, tx, 0)
+	ev := model.NewEventAt(initState, tx, 0, e.clock.Now())
 
 	// отправляем сообщение в очередь
 	initStateProcessor.Publish(ctx, ev)
 
-	return nil
+	return tx, nil
 }
 
 // Transit создает событие на проведение транзакции из одного состояния в другое
@@ -285,6 +463,16 @@ func (e *Engine) Transit(ctx context.Context, tx model.Tx, newState model.State)
 	// текущее состояние
 	currState := tx.State()
 
+	// новое состояние должно принадлежать версии модели, в которой проводится транзакция
+	versionState, _ := e.ResolveVersion(ctx, newState.Name(), tx.ModelVersion())
+	if versionState == nil {
+		transitTx.LogFields(log.Message("state not found in tx model version"))
+
+		return status.Errorf(codes.Internal, "%s not found in model version %s", newState.Name(), tx.ModelVersion())
+	}
+
+	newState = versionState
+
 	if currState == newState {
 		transitTx.LogFields(log.Message("tx already in this state"))
 
@@ -300,7 +488,7 @@ func (e *Engine) Transit(ctx context.Context, tx model.Tx, newState model.State)
 	}
 
 	// обработчик нового состояния
-	nextStateProcessor, ok := e.states[newState]
+	nextStateProcessor, ok := e.processor(newState)
 	if !ok {
 		transitTx.LogFields(log.Message("state processor not initialized"))
 
@@ -310,10 +498,10 @@ func (e *Engine) Transit(ctx context.Context, tx model.Tx, newState model.State)
 	tx.SetState(newState)
 
 	// создаем событие для обработки
-	ev := model.NewEvent(newState, tx, 0)
+	ev := model.NewEventAt(newState, tx, 0, e.clock.Now())
 
-	// обновляем транзакцию в БД
-	err := e.repo.UpdateTransaction(ctx, tx, currState.Name())
+	// обновляем транзакцию в БД (вместе с записью о переходе)
+	err := e.saveTransition(ctx, tx, currState.Name())
 	if err != nil {
 		return status.Errorf(codes.Internal, "update transaction error: %s", err.Error())
 	}
@@ -323,3 +511,75 @@ func (e *Engine) Transit(ctx context.Context, tx model.Tx, newState model.State)
 
 	return nil
 }
+
+// CircuitBreakers состояния circuit breaker очередей (в рамках текущей реплики), отсортированные по очереди
+func (e *Engine) CircuitBreakers() []CircuitBreakerStatus {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	statuses := make([]CircuitBreakerStatus, 0, len(e.queues))
+
+	for queueName, sp := range e.queues {
+		if cb := sp.circuitBreaker(); cb != nil {
+			statuses = append(statuses, cb.status(queueName))
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Queue < statuses[j].Queue
+	})
+
+	return statuses
+}
+
+// processor обработчик очереди состояния
+func (e *Engine) processor(state model.State) (*StateProcessor, bool) {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	sp, ok := e.states[state]
+
+	return sp, ok
+}
+
+// validateStates проверяет ограничения частоты, настройки circuit breaker и поддержку компенсаций состояний модели
+func (e *Engine) validateStates(mdl model.Model) error {
+	for _, s := range mdl.States() {
+		if limit := s.RateLimit(); limit != nil {
+			if e.limiter == nil {
+				return fmt.Errorf("state %s has rate limit, but engine limiter is not configured", s.Name())
+			}
+
+			if limit.Rate <= 0 {
+				return fmt.Errorf("state %s has invalid rate limit %v", s.Name(), limit.Rate)
+			}
+		}
+
+		if cb := s.CircuitBreaker(); cb != nil && (cb.FailureThreshold <= 0 || cb.OpenTimeout <= 0) {
+			return fmt.Errorf("state %s has invalid circuit breaker settings", s.Name())
+		}
+
+		if _, ok := s.(model.Compensator); ok {
+			if _, ok := e.repo.(model.EventRepository); !ok {
+				return fmt.Errorf("state %s has compensation handler: %w", s.Name(), errNoEventRepository)
+			}
+		}
+	}
+
+	return nil
+}
+
+// createTxIdempotent сохраняет транзакцию вместе с ключом идемпотентности
+func (e *Engine) createTxIdempotent(ctx context.Context, tx model.Tx, key string) (model.Tx, bool, error) {
+	repo, ok := e.repo.(model.IdempotencyRepository)
+	if !ok {
+		return nil, false, status.Error(codes.Unimplemented, "repository doesn't support idempotency keys")
+	}
+
+	stored, created, err := repo.CreateTransactionIdempotent(ctx, tx, key, e.idempotencyKeyRetention)
+	if err != nil {
+		return nil, false, status.Errorf(codes.Internal, "create transaction error: %s", err.Error())
+	}
+
+	return stored, created, nil
+}
@@ -0,0 +1,117 @@
+package fsmctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"fsm-framework/fsm-engine/migration"
+)
+
+// defaultMigrationsDir директория миграций проекта (совпадает с fsm-generator -migrations)
+const defaultMigrationsDir = "internal/fsm/migrations"
+
+const usage = `usage: fsmctl <command> [flags]
+
+commands:
+  migrate  move stored txs from removed or renamed states and re-publish their events,
+           specs: spec.yaml ... or -model name (internal/fsm/migrations/<name>.yaml)
+
+flags:
+`
+
+var (
+	// ErrUnknownCommand команда не поддерживается
+	ErrUnknownCommand = errors.New("fsmctl: unknown command")
+	// ErrMigrationFailed часть транзакций не удалось перевести, подробности в отчете
+	ErrMigrationFailed = errors.New("fsmctl: some transactions failed to migrate")
+)
+
+// Migrator применяет миграции хранимых транзакций (fsmengine.Engine)
+type Migrator interface {
+	Migrate(ctx context.Context, spec *migration.Spec, dryRun bool) (*migration.Report, error)
+}
+
+// Run исполняет команду fsmctl, args – аргументы без названия программы, отчеты выводятся в out.
+// Сервис встраивает команду в свой бинарник после инициализации движка и моделей:
+//
+//	if len(os.Args) > 1 && os.Args[1] == "fsmctl" {
+//	    err = fsmctl.Run(ctx, engine, os.Args[2:], os.Stdout)
+//	}
+func Run(ctx context.Context, engine Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(out, usage)
+
+		return ErrUnknownCommand
+	}
+
+	switch args[0] {
+	case "migrate":
+		return migrate(ctx, engine, args[1:], out)
+	default:
+		fmt.Fprint(out, usage)
+
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, args[0])
+	}
+}
+
+// migrate применяет (или выводит план в режиме -dry-run) миграции из переданных файлов
+func migrate(ctx context.Context, engine Migrator, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("fsmctl migrate", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprint(out, usage)
+		fs.PrintDefaults()
+	}
+
+	dryRun := fs.Bool("dry-run", false, "print migration plan without changing transactions")
+	modelName := fs.String("model", "", "model name, spec is read from -migrations directory")
+	migrationsDir := fs.String("migrations", defaultMigrationsDir, "tx migrations directory")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	if *modelName != "" {
+		paths = append(paths, filepath.Join(*migrationsDir, *modelName+".yaml"))
+	}
+
+	if len(paths) == 0 {
+		fs.Usage()
+
+		return errors.New("fsmctl: migration spec is required")
+	}
+
+	var failed int
+
+	for _, path := range paths {
+		spec, err := migration.Load(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		report, err := engine.Migrate(ctx, spec, *dryRun)
+		if report != nil {
+			if _, writeErr := report.WriteTo(out); writeErr != nil {
+				return writeErr
+			}
+
+			failed += report.Failed()
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if failed > 0 {
+		return ErrMigrationFailed
+	}
+
+	return nil
+}
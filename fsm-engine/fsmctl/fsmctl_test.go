@@ -0,0 +1,104 @@
+package fsmctl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/migration"
+)
+
+type migratorFunc func(ctx context.Context, spec *migration.Spec, dryRun bool) (*migration.Report, error)
+
+func (f migratorFunc) Migrate(ctx context.Context, spec *migration.Spec, dryRun bool) (*migration.Report, error) {
+	return f(ctx, spec, dryRun)
+}
+
+func writeSpec(t *testing.T, dir string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "first.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("states:\n  - from: THIRD\n    to: SECOND\n"), 0o600))
+
+	return path
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	path := writeSpec(t, dir)
+	txID := uuid.New()
+
+	tests := []struct {
+		name   string
+		args   []string
+		failed bool
+		output string
+		err    error
+	}{
+		{
+			name:   "dry run",
+			args:   []string{"migrate", "-dry-run", path},
+			output: "dry run: 1 transaction(s) to migrate\n" + txID.String() + " THIRD -> SECOND planned\n",
+		},
+		{
+			name:   "apply by model name",
+			args:   []string{"migrate", "-migrations", dir, "-model", "first"},
+			output: "migrated: 1 transaction(s), failed: 0\n" + txID.String() + " THIRD -> SECOND applied\n",
+		},
+		{
+			name:   "failed",
+			args:   []string{"migrate", path},
+			failed: true,
+			output: "migrated: 0 transaction(s), failed: 1\n" + txID.String() + " THIRD -> SECOND failed: locked\n",
+			err:    ErrMigrationFailed,
+		},
+		{
+			name: "unknown command",
+			args: []string{"rollback"},
+			err:  ErrUnknownCommand,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := migratorFunc(func(ctx context.Context, spec *migration.Spec,
+				dryRun bool) (*migration.Report, error) {
+				// модель определяется по названию файла
+				assert.Equal(t, "first", spec.Model)
+				assert.Equal(t, []*migration.StateMigration{{From: "THIRD", To: "SECOND"}}, spec.States)
+
+				item := &migration.ReportItem{TxID: txID, From: "THIRD", To: "SECOND"}
+
+				switch {
+				case dryRun:
+				case tt.failed:
+					item.Err = errors.New("locked")
+				default:
+					item.Applied = true
+				}
+
+				return &migration.Report{DryRun: dryRun, Items: []*migration.ReportItem{item}}, nil
+			})
+
+			out := &bytes.Buffer{}
+
+			err := Run(context.Background(), engine, tt.args, out)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tt.output != "" {
+				assert.Equal(t, tt.output, out.String())
+			}
+		})
+	}
+}
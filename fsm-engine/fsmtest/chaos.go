@@ -0,0 +1,103 @@
+package fsmtest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	"fsm-framework/fsm-engine/queue"
+)
+
+// chaos источник случайных сбоев доставки: дубликаты публикаций и повторная доставка подтвержденных сообщений
+type chaos struct {
+	m   sync.Mutex
+	rnd *rand.Rand
+
+	duplicateRate  float64
+	redeliveryRate float64
+}
+
+func newChaos(seed int64, duplicateRate, redeliveryRate float64) *chaos {
+	return &chaos{
+		rnd:            rand.New(rand.NewSource(seed)), // #nosec
+		duplicateRate:  duplicateRate,
+		redeliveryRate: redeliveryRate,
+	}
+}
+
+// hit событие с вероятностью rate
+func (c *chaos) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.rnd.Float64() < rate
+}
+
+func (c *chaos) intn(n int) int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.rnd.Intn(n)
+}
+
+func (c *chaos) float64() float64 {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.rnd.Float64()
+}
+
+// chaosBroker брокер, дублирующий публикации и повторно доставляющий подтвержденные сообщения
+// (семантика at-least-once в худшем ее проявлении)
+type chaosBroker struct {
+	queue.Broker
+	chaos *chaos
+}
+
+func (b *chaosBroker) Channel() (queue.Channel, error) {
+	ch, err := b.Broker.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return &chaosChannel{Channel: ch, chaos: b.chaos}, nil
+}
+
+type chaosChannel struct {
+	queue.Channel
+	chaos *chaos
+}
+
+func (c *chaosChannel) Publish(ctx context.Context, queueName string, body []byte) {
+	c.Channel.Publish(ctx, queueName, body)
+
+	if c.chaos.hit(c.chaos.duplicateRate) {
+		c.Channel.Publish(ctx, queueName, body)
+	}
+}
+
+func (c *chaosChannel) Consume(ctx context.Context, queueName string, handler queue.Handler) error {
+	return c.Channel.Consume(ctx, queueName, func(ctx context.Context, d queue.Delivery) error {
+		return handler(ctx, &chaosDelivery{Delivery: d, chaos: c.chaos})
+	})
+}
+
+type chaosDelivery struct {
+	queue.Delivery
+	chaos *chaos
+}
+
+// Ack подтверждение может "потеряться", и сообщение будет доставлено повторно
+func (d *chaosDelivery) Ack(ctx context.Context) {
+	if d.chaos.hit(d.chaos.redeliveryRate) {
+		d.Delivery.Reject(ctx)
+
+		return
+	}
+
+	d.Delivery.Ack(ctx)
+}
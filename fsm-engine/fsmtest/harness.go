@@ -0,0 +1,261 @@
+package fsmtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fsmengine "fsm-framework/fsm-engine"
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/lock/maplock"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/queue"
+	"fsm-framework/fsm-engine/queue/memqueue"
+	"fsm-framework/fsm-engine/ratelimit/maprate"
+	"fsm-framework/fsm-engine/repository/memrepo"
+)
+
+// Timeout максимальное время ожидания обработки всех событий в RunUntilIdle
+var Timeout = 10 * time.Second
+
+// ErrInjectedFailure паника обработчика, внедренная FailHandler
+var ErrInjectedFailure = errors.New("fsmtest: injected handler failure")
+
+// Harness сквозной тестовый стенд моделей: настоящий fsmengine.Engine с брокером, локером и репозиторием в памяти.
+// Задержки обработки (повторы, circuit breaker, rate limit) идут по управляемым часам Clock и не ждут реального времени
+type Harness struct {
+	t testing.TB
+
+	Engine *fsmengine.Engine
+	Repo   *memrepo.Repository
+	Broker *memqueue.Broker
+	Clock  *clock.Fake
+
+	callbacks *callbackRecorder
+
+	m sync.Mutex
+	// breakers изменения состояний circuit breaker очередей в порядке получения
+	breakers []fsmengine.CircuitBreakerStatus
+	// failures оставшееся количество внедренных неудач по состояниям (< 0 – всегда)
+	failures map[string]int
+	// stubs подмененные обработчики по состояниям
+	stubs map[string]fsmengine.HandlerFunc
+}
+
+// New запускает движок с переданными моделями (версии одной модели – от старых к новым),
+// clone – глубокое копирование транзакции сервиса для репозитория. Движок останавливается по завершении теста
+func New(t testing.TB, clone memrepo.CloneFunc, models ...model.Model) *Harness {
+	t.Helper()
+
+	return newHarness(t, clone, nil, models)
+}
+
+// newHarness chaos – внедрение сбоев доставки (может быть nil)
+func newHarness(t testing.TB, clone memrepo.CloneFunc, c *chaos, models []model.Model) *Harness {
+	t.Helper()
+
+	locker, err := maplock.NewLocker()
+	require.NoError(t, err)
+
+	clk := clock.NewFake(time.Now())
+
+	// ограничение частоты состояний идет по тем же управляемым часам
+	limiter, err := maprate.NewLimiter(clk)
+	require.NoError(t, err)
+
+	h := &Harness{
+		t:         t,
+		Repo:      memrepo.New(clone, memrepo.WithClock(clk)),
+		Broker:    memqueue.NewBroker(memqueue.WithClock(clk)),
+		Clock:     clk,
+		callbacks: &callbackRecorder{clone: clone},
+		failures:  make(map[string]int),
+		stubs:     make(map[string]fsmengine.HandlerFunc),
+	}
+
+	var broker queue.Broker = h.Broker
+	if c != nil {
+		broker = &chaosBroker{Broker: h.Broker, chaos: c}
+	}
+
+	h.Engine, err = fsmengine.New(fsmengine.Config{
+		Repository:         h.Repo,
+		Locker:             locker,
+		Limiter:            limiter,
+		Broker:             broker,
+		CallbackManager:    h.callbacks,
+		Clock:              h.Clock,
+		HandlerInterceptor: h.intercept,
+		CircuitBreakerHook: h.recordCircuitBreaker,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		h.Engine.Stop(context.Background())
+	})
+
+	for _, mdl := range models {
+		require.NoError(t, h.Engine.AddModel(context.Background(), mdl))
+	}
+
+	return h
+}
+
+// CreateTx создает транзакцию в начальном состоянии, не дожидаясь обработки
+func (h *Harness) CreateTx(tx model.Tx, initState model.State, opts ...model.CreateTxOption) model.Tx {
+	h.t.Helper()
+
+	created, err := h.Engine.CreateTx(context.Background(), tx, initState, opts...)
+	require.NoError(h.t, err)
+
+	return created
+}
+
+// RunUntilIdle ожидает, пока все события будут обработаны: очереди пусты и ни один обработчик не выполняется.
+// Если остались только отложенные сообщения брокера, часы сдвигаются до времени готовности первого из них
+func (h *Harness) RunUntilIdle() {
+	h.t.Helper()
+
+	deadline := time.Now().Add(Timeout)
+
+	for !h.Broker.Idle() {
+		if readyAt, ok := h.Broker.NextDelayed(); ok {
+			h.Clock.Advance(readyAt.Sub(h.Clock.Now()))
+
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			h.t.Fatalf("fsmtest: events are still processing after %s", Timeout)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Tx текущая сохраненная версия транзакции
+func (h *Harness) Tx(tx model.Tx) model.Tx {
+	h.t.Helper()
+
+	stored, err := h.Repo.Transaction(context.Background(), tx.ID())
+	require.NoError(h.t, err)
+
+	return stored
+}
+
+// Callbacks транзакции, по которым были отправлены обратные вызовы, в порядке отправки
+func (h *Harness) Callbacks() []model.Tx {
+	return h.callbacks.sent()
+}
+
+// AssertPath проверяет последовательность состояний, пройденных транзакцией (начиная с начального)
+func (h *Harness) AssertPath(tx model.Tx, states ...model.State) bool {
+	h.t.Helper()
+
+	want := make([]string, 0, len(states))
+	for _, s := range states {
+		want = append(want, s.Name())
+	}
+
+	return assert.Equal(h.t, want, h.Repo.Path(tx.ID()), "tx %s path", tx.ID())
+}
+
+// AssertStatus проверяет текущий статус транзакции
+func (h *Harness) AssertStatus(tx model.Tx, status model.TxStatus) bool {
+	h.t.Helper()
+
+	return assert.Equal(h.t, status, h.Tx(tx).Status(), "tx %s status", tx.ID())
+}
+
+// CircuitBreakerChanges изменения состояний circuit breaker очередей в порядке получения (Config.CircuitBreakerHook)
+func (h *Harness) CircuitBreakerChanges() []fsmengine.CircuitBreakerStatus {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	return append([]fsmengine.CircuitBreakerStatus(nil), h.breakers...)
+}
+
+func (h *Harness) recordCircuitBreaker(ctx context.Context, status fsmengine.CircuitBreakerStatus) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.breakers = append(h.breakers, status)
+}
+
+// FailHandler следующие times вызовов обработчика состояния завершатся паникой ErrInjectedFailure
+// (times < 0 – все вызовы, 0 – отменяет внедрение)
+func (h *Harness) FailHandler(state model.State, times int) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.failures[state.Name()] = times
+}
+
+// StubHandler подменяет обработчик состояния (всех версий модели), nil – восстанавливает исходный
+func (h *Harness) StubHandler(state model.State, handler fsmengine.HandlerFunc) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if handler == nil {
+		delete(h.stubs, state.Name())
+
+		return
+	}
+
+	h.stubs[state.Name()] = handler
+}
+
+func (h *Harness) intercept(ctx context.Context, state model.State, ev *model.Event,
+	handler fsmengine.HandlerFunc) model.State {
+	h.m.Lock()
+
+	failures := h.failures[state.Name()]
+	if failures > 0 {
+		h.failures[state.Name()]--
+	}
+
+	stub := h.stubs[state.Name()]
+
+	h.m.Unlock()
+
+	if failures != 0 {
+		panic(ErrInjectedFailure)
+	}
+
+	if stub != nil {
+		return stub(ctx, ev)
+	}
+
+	return handler(ctx, ev)
+}
+
+// callbackRecorder запоминает отправленные обратные вызовы вместо их отправки
+type callbackRecorder struct {
+	clone memrepo.CloneFunc
+
+	m   sync.Mutex
+	txs []model.Tx
+}
+
+func (c *callbackRecorder) Send(ctx context.Context, tx model.Tx) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.txs = append(c.txs, c.clone(tx))
+}
+
+func (c *callbackRecorder) Stop() error {
+	return nil
+}
+
+func (c *callbackRecorder) sent() []model.Tx {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return append([]model.Tx(nil), c.txs...)
+}
@@ -0,0 +1,124 @@
+package fsmtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/model"
+	test_model "fsm-framework/fsm-engine/test-model"
+)
+
+func finish(ctx context.Context, ev *model.Event) model.State {
+	return nil
+}
+
+func TestHarnessPath(t *testing.T) {
+	h := New(t, CloneTx, test_model.Model)
+
+	h.StubHandler(test_model.FooState, func(ctx context.Context, ev *model.Event) model.State {
+		return test_model.BarState
+	})
+	h.StubHandler(test_model.BarState, finish)
+
+	tx := h.CreateTx(NewTx(), test_model.FooState)
+	h.RunUntilIdle()
+
+	h.AssertPath(tx, test_model.FooState, test_model.BarState)
+	h.AssertStatus(tx, model.TxStatusDone)
+}
+
+func TestHarnessFallback(t *testing.T) {
+	h := New(t, CloneTx, test_model.Model)
+
+	h.FailHandler(test_model.FooState, -1)
+	h.StubHandler(test_model.BarState, finish)
+
+	tx := h.CreateTx(NewTx(), test_model.FooState)
+	h.RunUntilIdle()
+
+	// исчерпав попытки, транзакция уходит в fallback состояние
+	h.AssertPath(tx, test_model.FooState, test_model.BarState)
+	h.AssertStatus(tx, model.TxStatusDone)
+
+	var retries int
+
+	for _, ev := range h.Repo.EventsOf(tx.ID()) {
+		if ev.StartState == test_model.FooState.Name() {
+			retries++
+		}
+	}
+
+	assert.Equal(t, model.EventRetryMaxCount-1, retries)
+}
+
+func TestHarnessIdempotencyKeyExpiry(t *testing.T) {
+	h := New(t, CloneTx, test_model.Model)
+	h.StubHandler(test_model.FooState, finish)
+
+	first := h.CreateTx(NewTx(), test_model.FooState, model.WithIdempotencyKey("key"))
+	h.RunUntilIdle()
+
+	again := h.CreateTx(NewTx(), test_model.FooState, model.WithIdempotencyKey("key"))
+	assert.Equal(t, first.ID(), again.ID())
+
+	// ключи истекают по часам стенда
+	h.Clock.Advance(24 * time.Hour)
+
+	second := h.CreateTx(NewTx(), test_model.FooState, model.WithIdempotencyKey("key"))
+	h.RunUntilIdle()
+
+	assert.NotEqual(t, first.ID(), second.ID())
+	assert.Len(t, h.Repo.Transactions(), 2)
+}
+
+func TestWalkSeed(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		seed int64
+		want int64
+		err  bool
+	}{
+		{name: "default", want: defaultWalkSeed},
+		{name: "config", seed: 7, want: 7},
+		{name: "env overrides config", env: "42", seed: 7, want: 42},
+		{name: "invalid env", env: "seed", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(SeedEnv, tt.env)
+
+			seed, err := walkSeed(tt.seed)
+			if tt.err {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, seed)
+		})
+	}
+}
+
+func TestRandomWalk(t *testing.T) {
+	h := RandomWalk(t, RandomWalkConfig{
+		Models:         []model.Model{test_model.Model},
+		Clone:          CloneTx,
+		NewTx:          func(id uuid.UUID) model.Tx { return &Tx{TxID: id} },
+		Transactions:   50,
+		MaxSteps:       10,
+		PanicRate:      0.1,
+		TimeoutRate:    0.05,
+		DuplicateRate:  0.1,
+		RedeliveryRate: 0.1,
+	})
+
+	assert.Len(t, h.Repo.Transactions(), 50)
+}
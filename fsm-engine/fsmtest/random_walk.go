@@ -0,0 +1,244 @@
+package fsmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/repository/memrepo"
+)
+
+const (
+	defaultWalkTransactions = 100
+	defaultWalkMaxSteps     = 20
+	// defaultWalkSeed seed по умолчанию: обход воспроизводим от запуска к запуску
+	defaultWalkSeed = 1
+)
+
+// SeedEnv переменная окружения, переопределяющая RandomWalkConfig.Seed
+// (повтор упавшего обхода или проверка на других seed: FSMTEST_SEED=42 go test ./...)
+const SeedEnv = "FSMTEST_SEED"
+
+// ErrInjectedTimeout обработчик "завис" дольше CancellationTTL состояния (время идет по часам стенда)
+var ErrInjectedTimeout = errors.New("fsmtest: injected handler timeout")
+
+// RandomWalkConfig параметры случайного обхода моделей, вероятности задаются в интервале [0, 1]
+type RandomWalkConfig struct {
+	// Models проверяемые модели (версии одной модели – от старых к новым)
+	Models []model.Model
+	// Clone глубокое копирование транзакции сервиса
+	Clone memrepo.CloneFunc
+	// NewTx создает пустую транзакцию сервиса
+	NewTx func(id uuid.UUID) model.Tx
+	// Transactions количество транзакций (по умолчанию 100)
+	Transactions int
+	// MaxSteps количество вызовов обработчиков на транзакцию, после которого обработчики только падают,
+	// чтобы обход циклов модели завершался (по умолчанию 20)
+	MaxSteps int
+	// Seed начальное значение генератора случайных чисел (по умолчанию 1), переопределяется SeedEnv,
+	// выводится в лог теста
+	Seed int64
+
+	// PanicRate вероятность паники обработчика
+	PanicRate float64
+	// TimeoutRate вероятность "зависания" обработчика с последующей ошибкой
+	TimeoutRate float64
+	// DuplicateRate вероятность повторной публикации события
+	DuplicateRate float64
+	// RedeliveryRate вероятность повторной доставки уже подтвержденного события
+	RedeliveryRate float64
+}
+
+// walkSeed seed обхода: SeedEnv, если задана, иначе seed из конфигурации или defaultWalkSeed
+func walkSeed(seed int64) (int64, error) {
+	if env := os.Getenv(SeedEnv); env != "" {
+		seed, err := strconv.ParseInt(env, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("fsmtest: invalid %s: %w", SeedEnv, err)
+		}
+
+		return seed, nil
+	}
+
+	if seed == 0 {
+		return defaultWalkSeed, nil
+	}
+
+	return seed, nil
+}
+
+// RandomWalk создает транзакции в случайных начальных состояниях моделей, обработчики которых возвращают
+// случайные разрешенные состояния, падают или зависают, а брокер дублирует и повторно доставляет события.
+// После обработки всех событий проверяются инварианты:
+// каждая транзакция завершена в конечном состоянии либо с ошибкой, переходы разрешены CanTransitIn,
+// события одной транзакции не обрабатываются параллельно
+func RandomWalk(t *testing.T, cfg RandomWalkConfig) *Harness {
+	t.Helper()
+
+	if cfg.Transactions == 0 {
+		cfg.Transactions = defaultWalkTransactions
+	}
+
+	if cfg.MaxSteps == 0 {
+		cfg.MaxSteps = defaultWalkMaxSteps
+	}
+
+	seed, err := walkSeed(cfg.Seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Seed = seed
+
+	t.Logf("fsmtest: random walk seed %d", cfg.Seed)
+
+	w := &walker{
+		cfg:      cfg,
+		chaos:    newChaos(cfg.Seed, cfg.DuplicateRate, cfg.RedeliveryRate),
+		steps:    make(map[uuid.UUID]int, cfg.Transactions),
+		inFlight: make(map[uuid.UUID]bool),
+	}
+
+	w.h = newHarness(t, cfg.Clone, w.chaos, cfg.Models)
+
+	var initStates []model.State
+
+	for _, mdl := range cfg.Models {
+		for _, s := range mdl.States() {
+			w.h.StubHandler(s, w.handle)
+
+			if s.IsInitial() {
+				initStates = append(initStates, s)
+			}
+		}
+	}
+
+	if len(initStates) == 0 {
+		t.Fatal("fsmtest: models have no initial states")
+	}
+
+	txs := make([]model.Tx, 0, cfg.Transactions)
+
+	for i := 0; i < cfg.Transactions; i++ {
+		initState := initStates[w.chaos.intn(len(initStates))]
+		txs = append(txs, w.h.CreateTx(cfg.NewTx(uuid.New()), initState))
+	}
+
+	w.h.RunUntilIdle()
+
+	for _, tx := range txs {
+		w.checkTx(tx)
+	}
+
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	for _, violation := range w.violations {
+		t.Error(violation)
+	}
+
+	return w.h
+}
+
+type walker struct {
+	h     *Harness
+	cfg   RandomWalkConfig
+	chaos *chaos
+
+	m sync.Mutex
+	// steps количество вызовов обработчиков по транзакциям
+	steps map[uuid.UUID]int
+	// inFlight транзакции, обработчик которых выполняется прямо сейчас
+	inFlight   map[uuid.UUID]bool
+	violations []string
+}
+
+// handle случайный обработчик любого состояния модели
+func (w *walker) handle(ctx context.Context, ev *model.Event) model.State {
+	txID := ev.Tx.ID()
+	// актуальная транзакция загружена из репозитория, ее состояние – состояние события в версии модели транзакции
+	state := ev.Tx.State()
+
+	w.m.Lock()
+
+	if w.inFlight[txID] {
+		w.violations = append(w.violations,
+			fmt.Sprintf("tx %s: event %s of %s is processed concurrently", txID, ev.ID, state.Name()))
+	}
+
+	w.inFlight[txID] = true
+	w.steps[txID]++
+	exhausted := w.steps[txID] > w.cfg.MaxSteps
+
+	w.m.Unlock()
+
+	defer func() {
+		w.m.Lock()
+		delete(w.inFlight, txID)
+		w.m.Unlock()
+	}()
+
+	r := w.chaos.float64()
+
+	switch {
+	case exhausted || r < w.cfg.PanicRate:
+		panic(ErrInjectedFailure)
+	case r < w.cfg.PanicRate+w.cfg.TimeoutRate:
+		w.h.Clock.Sleep(state.CancellationTTL())
+		panic(ErrInjectedTimeout)
+	}
+
+	var candidates []model.State
+
+	for _, s := range state.Model().States() {
+		if state.CanTransitIn(s) {
+			candidates = append(candidates, s)
+		}
+	}
+
+	// конечное состояние может завершить транзакцию
+	if state.IsSuccessFinal() || state.IsFailFinal() {
+		candidates = append(candidates, nil)
+	}
+
+	if len(candidates) == 0 {
+		panic(ErrInjectedFailure)
+	}
+
+	return candidates[w.chaos.intn(len(candidates))]
+}
+
+// checkTx проверяет инварианты завершенной транзакции
+func (w *walker) checkTx(tx model.Tx) {
+	stored := w.h.Tx(tx)
+	state := stored.State()
+
+	final := state.IsSuccessFinal() || state.IsFailFinal()
+	if stored.Status() != model.TxStatusError && !(stored.Status() == model.TxStatusDone && final) {
+		w.violate("tx %s: ends in %s with status %s", tx.ID(), state.Name(), stored.Status())
+	}
+
+	path := w.h.Repo.Path(tx.ID())
+	mdl := state.Model()
+
+	for i := 1; i < len(path); i++ {
+		from, to := mdl.Resolve(path[i-1]), mdl.Resolve(path[i])
+		if from == nil || to == nil || !from.CanTransitIn(to) {
+			w.violate("tx %s: illegal transition %s -> %s", tx.ID(), path[i-1], path[i])
+		}
+	}
+}
+
+func (w *walker) violate(format string, args ...interface{}) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.violations = append(w.violations, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,57 @@
+package fsmtest
+
+import (
+	"github.com/google/uuid"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+var _ model.Tx = &Tx{}
+
+// Tx транзакция без собственных полей сервиса для тестов моделей, движка и репозиториев.
+// Транзакции сервиса с дополнительными полями могут встраивать *Tx
+type Tx struct {
+	// TxID идентификатор транзакции (открыт для чтения репозиториями, например pgrepo.TxMapper.Dest)
+	TxID        uuid.UUID
+	state       model.State
+	status      model.TxStatus
+	traceID     string
+	spanID      string
+	callbackURL string
+	version     string
+	data        []byte
+}
+
+// NewTx транзакция с новым идентификатором
+func NewTx() *Tx {
+	return &Tx{TxID: uuid.New()}
+}
+
+// CloneTx глубокое копирование *Tx для репозитория в памяти и стенда (memrepo.CloneFunc)
+func CloneTx(tx model.Tx) model.Tx {
+	return tx.(*Tx).Clone()
+}
+
+// Clone глубокая копия транзакции
+func (t *Tx) Clone() *Tx {
+	cp := *t
+	cp.data = append([]byte(nil), t.data...)
+
+	return &cp
+}
+
+func (t *Tx) ID() uuid.UUID                  { return t.TxID }
+func (t *Tx) State() model.State             { return t.state }
+func (t *Tx) SetState(s model.State)         { t.state = s }
+func (t *Tx) Status() model.TxStatus         { return t.status }
+func (t *Tx) SetStatus(s model.TxStatus)     { t.status = s }
+func (t *Tx) TraceID() string                { return t.traceID }
+func (t *Tx) SetTraceID(traceID string)      { t.traceID = traceID }
+func (t *Tx) SpanID() string                 { return t.spanID }
+func (t *Tx) SetSpanID(spanID string)        { t.spanID = spanID }
+func (t *Tx) CallbackURL() string            { return t.callbackURL }
+func (t *Tx) SetCallbackURL(url string)      { t.callbackURL = url }
+func (t *Tx) ModelVersion() string           { return t.version }
+func (t *Tx) SetModelVersion(version string) { t.version = version }
+func (t *Tx) ContextData() []byte            { return t.data }
+func (t *Tx) SetContextData(data []byte)     { t.data = data }
@@ -0,0 +1,95 @@
+package fsmengine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	fsmengine "fsm-framework/fsm-engine"
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/lock/maplock"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/queue/memqueue"
+	"fsm-framework/fsm-engine/repository/memrepo"
+)
+
+func TestCreateTxIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	repo := memrepo.New(fsmtest.CloneTx, memrepo.WithClock(clk))
+	broker := memqueue.NewBroker(memqueue.WithClock(clk))
+
+	locker, err := maplock.NewLocker()
+	require.NoError(t, err)
+
+	mdl := newTestModel("idempotent", "1")
+	created := mdl.state("CREATED")
+	created.initial = true
+	created.success = true
+
+	engine, err := fsmengine.New(fsmengine.Config{
+		Repository:              repo,
+		Locker:                  locker,
+		Broker:                  broker,
+		CallbackManager:         nopCallbacks{},
+		Clock:                   clk,
+		IdempotencyKeyRetention: time.Hour,
+	})
+	require.NoError(t, err)
+	require.NoError(t, engine.AddModel(ctx, mdl))
+
+	defer engine.Stop(ctx)
+
+	published := func() int {
+		return broker.Stats(created.Queue()).Published
+	}
+
+	first, err := engine.CreateTx(ctx, fsmtest.NewTx(), created, model.WithIdempotencyKey("key"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, published())
+
+	// повторный ключ: возвращается сохраненная транзакция, событие повторно не публикуется
+	again, err := engine.CreateTx(ctx, fsmtest.NewTx(), created, model.WithIdempotencyKey("key"))
+	require.NoError(t, err)
+	assert.Equal(t, first.ID(), again.ID())
+	assert.Equal(t, 1, published())
+
+	// другой ключ: новая транзакция
+	other, err := engine.CreateTx(ctx, fsmtest.NewTx(), created, model.WithIdempotencyKey("other"))
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID(), other.ID())
+	assert.Equal(t, 2, published())
+
+	// по истечении времени хранения ключ создает новую транзакцию
+	clk.Advance(time.Hour)
+
+	expired, err := engine.CreateTx(ctx, fsmtest.NewTx(), created, model.WithIdempotencyKey("key"))
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID(), expired.ID())
+	assert.Equal(t, 3, published())
+
+	assert.Len(t, repo.Transactions(), 3)
+}
+
+func TestCreateTxIdempotencyKeyUnsupported(t *testing.T) {
+	ctx := context.Background()
+
+	mdl := newTestModel("idempotent", "1")
+	created := mdl.state("CREATED")
+	created.initial = true
+	created.success = true
+
+	engine := basicEngine(t)
+	require.NoError(t, engine.AddModel(ctx, mdl))
+
+	defer engine.Stop(ctx)
+
+	_, err := engine.CreateTx(ctx, fsmtest.NewTx(), created, model.WithIdempotencyKey("key"))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
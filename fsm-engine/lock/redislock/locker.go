@@ -26,6 +26,26 @@ type Locker struct {
 }
 
 func NewLocker(addr []string, password string, appName string) (lock.Locker, error) {
+	client, err := NewClient(addr, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLockerWithClient(client, appName), nil
+}
+
+// NewLockerWithClient создает локер поверх существующего клиента (например, общего с redisrate),
+// клиент закрывается вместе с локером
+func NewLockerWithClient(client redis.UniversalClient, appName string) lock.Locker {
+	return &Locker{
+		redisClient: client,
+		redisLocker: rl.New(client),
+		appName:     appName,
+	}
+}
+
+// NewClient создает клиент redis (cluster при нескольких адресах) и проверяет соединение
+func NewClient(addr []string, password string) (redis.UniversalClient, error) {
 	var client redis.UniversalClient
 
 	if len(addr) == 1 {
@@ -64,13 +84,7 @@ func NewLocker(addr []string, password string, appName string) (lock.Locker, err
 		return nil, fmt.Errorf("redis ping error: %w", err)
 	}
 
-	redisLocker := rl.New(client)
-
-	return &Locker{
-		redisClient: client,
-		redisLocker: redisLocker,
-		appName:     appName,
-	}, nil
+	return client, nil
 }
 
 func (l *Locker) Close() error {
@@ -0,0 +1,118 @@
+package fsmengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+
+	"fsm-framework/fsm-engine/migration"
+	"fsm-framework/fsm-engine/model"
+	zlog "fsm-framework/misk/logger"
+)
+
+// Migrate переводит хранимые транзакции из удаленных или переименованных состояний в новые
+// и заново публикует события на их обработку. В режиме dryRun изменения не применяются, отчет содержит план.
+// Репозиторий должен реализовывать model.MigrationRepository
+func (e *Engine) Migrate(ctx context.Context, spec *migration.Spec, dryRun bool) (*migration.Report, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "migrating txs", opentracing.Tag{
+		Key:   "model",
+		Value: spec.Model,
+	}, opentracing.Tag{
+		Key:   "dry_run",
+		Value: dryRun,
+	})
+	defer span.Finish()
+
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, ok := e.repo.(model.MigrationRepository)
+	if !ok {
+		return nil, errors.New("repository doesn't implement model.MigrationRepository, txs can't be migrated")
+	}
+
+	report := &migration.Report{
+		DryRun: dryRun,
+	}
+
+	for _, sm := range spec.States {
+		toState, toModel := e.Resolve(ctx, sm.To)
+		if toState == nil {
+			return report, fmt.Errorf("migration state %s not found", sm.To)
+		}
+
+		if toModel.Name() != spec.Model {
+			return report, fmt.Errorf("migration state %s belongs to model %s", sm.To, toModel.Name())
+		}
+
+		txs, err := repo.TransactionsByState(ctx, sm.From)
+		if err != nil {
+			return report, fmt.Errorf("transactions in state %s: %w", sm.From, err)
+		}
+
+		for _, tx := range txs {
+			item := &migration.ReportItem{
+				TxID: tx.ID(),
+				From: sm.From,
+				To:   sm.To,
+			}
+
+			report.Items = append(report.Items, item)
+
+			if dryRun {
+				continue
+			}
+
+			item.Err = e.migrateTx(ctx, tx, sm.From, toState)
+			item.Applied = item.Err == nil
+		}
+	}
+
+	span.LogFields(log.Int("txs", len(report.Items)), log.Int("failed", report.Failed()))
+
+	return report, nil
+}
+
+// migrateTx переводит транзакцию в новое состояние последней версии модели и публикует событие
+func (e *Engine) migrateTx(ctx context.Context, tx model.Tx, fromState string, toState model.State) error {
+	ctx = zlog.FromLogger(zlog.Ctx(ctx).With().
+		Str("tx_id", tx.ID().String()).
+		Str("from_state", fromState).
+		Str("to_state", toState.Name()).Logger()).WithContext(ctx)
+
+	sp, ok := e.processor(toState)
+	if !ok {
+		return fmt.Errorf("%s not initialized", toState.Name())
+	}
+
+	txLock, err := e.locker.ObtainLock(ctx, lockPrefix+tx.ID().String())
+	if err != nil {
+		return fmt.Errorf("lock obtaining error: %w", err)
+	}
+
+	defer func() {
+		if releaseErr := txLock.Release(ctx); releaseErr != nil {
+			zlog.Ctx(ctx).Error().Err(releaseErr).Msg("migration lock can't be released")
+		}
+	}()
+
+	tx.SetState(toState)
+	tx.SetStatus(model.TxStatusPending)
+	tx.SetModelVersion(toState.Model().Version())
+
+	err = e.saveTransition(ctx, tx, fromState)
+	if err != nil {
+		return fmt.Errorf("update transaction error: %w", err)
+	}
+
+	sp.Publish(ctx, model.NewEventAt(toState, tx, 0, e.clock.Now()))
+
+	zlog.Ctx(ctx).Info().Msg("tx migrated")
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package fsmengine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/migration"
+	"fsm-framework/fsm-engine/model"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	calls := &recorder{}
+
+	// в предыдущей ревизии модели было состояние THIRD, в текущей его нет
+	removed := newTestModel("migrated", "0").state("THIRD")
+
+	mdl := newTestModel("migrated", "1")
+	created := mdl.state("CREATED", "SECOND")
+	created.initial = true
+
+	second := mdl.state("SECOND")
+	second.success = true
+	second.handler = func(ctx context.Context, ev *model.Event) model.State {
+		calls.add(ev.Tx.ID().String())
+
+		return nil
+	}
+
+	h := fsmtest.New(t, fsmtest.CloneTx, mdl)
+
+	txs := []*fsmtest.Tx{fsmtest.NewTx(), fsmtest.NewTx()}
+	for _, tx := range txs {
+		tx.SetState(removed)
+		tx.SetStatus(model.TxStatusPending)
+		tx.SetModelVersion("0")
+
+		require.NoError(t, h.Repo.CreateTransaction(ctx, tx))
+	}
+
+	spec := &migration.Spec{
+		Model:  "migrated",
+		States: []*migration.StateMigration{{From: "THIRD", To: "SECOND"}},
+	}
+
+	// dry run: только план, транзакции и очереди не меняются
+	report, err := h.Engine.Migrate(ctx, spec, true)
+	require.NoError(t, err)
+	require.Len(t, report.Items, len(txs))
+	assert.True(t, report.DryRun)
+
+	for i, item := range report.Items {
+		assert.Equal(t, txs[i].ID(), item.TxID)
+		assert.False(t, item.Applied)
+		assert.NoError(t, item.Err)
+		assert.Equal(t, "THIRD", h.Tx(txs[i]).State().Name())
+	}
+
+	h.RunUntilIdle()
+	assert.Zero(t, h.Broker.Stats(second.Queue()).Published)
+	assert.Empty(t, calls.list())
+
+	// применение: транзакции переведены в SECOND текущей версии и обработаны заново
+	report, err = h.Engine.Migrate(ctx, spec, false)
+	require.NoError(t, err)
+	require.Len(t, report.Items, len(txs))
+	assert.Zero(t, report.Failed())
+
+	h.RunUntilIdle()
+	assert.Equal(t, len(txs), h.Broker.Stats(second.Queue()).Published)
+	assert.ElementsMatch(t, []string{txs[0].ID().String(), txs[1].ID().String()}, calls.list())
+
+	for i, item := range report.Items {
+		assert.True(t, item.Applied)
+
+		h.AssertPath(txs[i], removed, second)
+		h.AssertStatus(txs[i], model.TxStatusDone)
+		assert.Equal(t, "1", h.Tx(txs[i]).ModelVersion())
+	}
+
+	// мигрировать больше нечего
+	report, err = h.Engine.Migrate(ctx, spec, true)
+	require.NoError(t, err)
+	assert.Empty(t, report.Items)
+}
+
+func TestMigrateRequiresMigrationRepository(t *testing.T) {
+	spec := &migration.Spec{
+		Model:  "migrated",
+		States: []*migration.StateMigration{{From: "THIRD", To: "SECOND"}},
+	}
+
+	_, err := basicEngine(t).Migrate(context.Background(), spec, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model.MigrationRepository")
+}
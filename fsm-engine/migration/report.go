@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Report результат применения (или пробного запуска) миграции
+type Report struct {
+	// DryRun изменения не применялись, отчет содержит только план миграции
+	DryRun bool
+	// Items транзакции, попавшие под миграцию
+	Items []*ReportItem
+}
+
+// ReportItem результат миграции одной транзакции
+type ReportItem struct {
+	TxID uuid.UUID
+	From string
+	To   string
+	// Applied транзакция переведена в новое состояние, событие опубликовано
+	Applied bool
+	// Err причина, по которой транзакцию не удалось перевести
+	Err error
+}
+
+// Failed кол-во транзакций, которые не удалось перевести
+func (r *Report) Failed() int {
+	var n int
+
+	for _, item := range r.Items {
+		if item.Err != nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+// WriteTo выводит отчет в текстовом виде
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	b := &strings.Builder{}
+
+	if r.DryRun {
+		fmt.Fprintf(b, "dry run: %d transaction(s) to migrate\n", len(r.Items))
+	} else {
+		fmt.Fprintf(b, "migrated: %d transaction(s), failed: %d\n", len(r.Items)-r.Failed(), r.Failed())
+	}
+
+	for _, item := range r.Items {
+		result := "planned"
+
+		switch {
+		case item.Err != nil:
+			result = "failed: " + item.Err.Error()
+		case item.Applied:
+			result = "applied"
+		}
+
+		fmt.Fprintf(b, "%s %s -> %s %s\n", item.TxID, item.From, item.To, result)
+	}
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
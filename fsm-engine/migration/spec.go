@@ -0,0 +1,91 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec описание миграции хранимых транзакций из удаленных или переименованных состояний модели
+type Spec struct {
+	// Model название модели, к которой применяется миграция
+	Model string `yaml:"model"`
+	// States список переходов из старых состояний в новые
+	States []*StateMigration `yaml:"states"`
+}
+
+// StateMigration перевод транзакций из старого состояния в новое
+type StateMigration struct {
+	// From название удаленного (переименованного) состояния
+	From string `yaml:"from"`
+	// To название состояния, в которое переводятся транзакции
+	To string `yaml:"to"`
+}
+
+// Parse декодирует yaml описание миграции
+func Parse(r io.Reader) (*Spec, error) {
+	spec := &Spec{}
+
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+
+	err := d.Decode(spec)
+	if err != nil {
+		return nil, fmt.Errorf("migration spec decode: %w", err)
+	}
+
+	return spec, nil
+}
+
+// Validate проверяет миграцию на непротиворечивость без учета модели
+func (s *Spec) Validate() error {
+	if len(s.States) == 0 {
+		return errors.New("migration spec has no states")
+	}
+
+	froms := make(map[string]bool, len(s.States))
+
+	for _, sm := range s.States {
+		if sm.From == "" || sm.To == "" {
+			return errors.New("migration state should have both from and to")
+		}
+
+		if sm.From == sm.To {
+			return fmt.Errorf("migration state %s migrates in itself", sm.From)
+		}
+
+		if froms[sm.From] {
+			return fmt.Errorf("migration state %s declared more than once", sm.From)
+		}
+
+		froms[sm.From] = true
+	}
+
+	return nil
+}
+
+// Load вычитывает описание миграции из файла, модель по умолчанию – название файла (<model_name>.yaml)
+func Load(path string) (*Spec, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	spec, err := Parse(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.Model == "" {
+		spec.Model = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return spec, nil
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+type BackoffStrategy string
+
+const (
+	// BackoffConstant одинаковая задержка перед каждым повтором
+	BackoffConstant BackoffStrategy = "constant"
+	// BackoffLinear задержка растет линейно: Base * retryN
+	BackoffLinear BackoffStrategy = "linear"
+	// BackoffExponential задержка растет экспоненциально: Base * 2^(retryN-1)
+	BackoffExponential BackoffStrategy = "exponential"
+)
+
+// Backoff стратегия задержки перед повторной обработкой события состояния
+type Backoff struct {
+	Strategy BackoffStrategy
+	// Base базовая задержка
+	Base time.Duration
+	// Max ограничение задержки сверху (0 – без ограничения)
+	Max time.Duration
+	// Jitter full jitter: случайная задержка в интервале [0, delay), разносит повторы разных транзакций во времени
+	Jitter bool
+}
+
+// Delay задержка перед повтором с номером retryN (отсчет с 1, первый повтор после неудачной обработки)
+func (b *Backoff) Delay(retryN int) time.Duration {
+	if retryN < 1 {
+		return 0
+	}
+
+	delay := b.Base
+
+	switch b.Strategy {
+	case BackoffLinear:
+		delay = b.Base * time.Duration(retryN)
+	case BackoffExponential:
+		for i := 1; i < retryN && (b.Max == 0 || delay < b.Max); i++ {
+			// защита от переполнения при большом кол-ве повторов
+			if delay > math.MaxInt64/2 {
+				delay = math.MaxInt64
+
+				break
+			}
+
+			delay *= 2
+		}
+	}
+
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+
+	if b.Jitter && delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay))) // #nosec
+	}
+
+	return delay
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		retryN  int
+		want    time.Duration
+	}{
+		{"no retry", Backoff{Strategy: BackoffExponential, Base: time.Second}, 0, 0},
+		{"constant first", Backoff{Strategy: BackoffConstant, Base: 5 * time.Second}, 1, 5 * time.Second},
+		{"constant third", Backoff{Strategy: BackoffConstant, Base: 5 * time.Second}, 3, 5 * time.Second},
+		{"empty strategy is constant", Backoff{Base: 5 * time.Second}, 4, 5 * time.Second},
+		{"linear first", Backoff{Strategy: BackoffLinear, Base: time.Second}, 1, time.Second},
+		{"linear third", Backoff{Strategy: BackoffLinear, Base: time.Second}, 3, 3 * time.Second},
+		{"exponential first", Backoff{Strategy: BackoffExponential, Base: time.Second}, 1, time.Second},
+		{"exponential fourth", Backoff{Strategy: BackoffExponential, Base: time.Second}, 4, 8 * time.Second},
+		{"linear max", Backoff{Strategy: BackoffLinear, Base: time.Second, Max: 2 * time.Second}, 3, 2 * time.Second},
+		{"exponential max", Backoff{Strategy: BackoffExponential, Base: time.Second, Max: 5 * time.Second}, 4,
+			5 * time.Second},
+		{"constant above max", Backoff{Strategy: BackoffConstant, Base: time.Minute, Max: time.Second}, 1, time.Second},
+		{"exponential overflow", Backoff{Strategy: BackoffExponential, Base: time.Second}, 100, math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.backoff.Delay(tt.retryN))
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		retryN  int
+		max     time.Duration
+	}{
+		{"constant", Backoff{Strategy: BackoffConstant, Base: time.Second, Jitter: true}, 2, time.Second},
+		{"linear", Backoff{Strategy: BackoffLinear, Base: time.Second, Jitter: true}, 3, 3 * time.Second},
+		{"exponential capped", Backoff{Strategy: BackoffExponential, Base: time.Second, Max: 4 * time.Second,
+			Jitter: true}, 10, 4 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				d := tt.backoff.Delay(tt.retryN)
+
+				assert.GreaterOrEqual(t, d, time.Duration(0))
+				assert.LessOrEqual(t, d, tt.max)
+			}
+		})
+	}
+
+	// нулевая задержка остается нулевой
+	assert.Zero(t, (&Backoff{Strategy: BackoffConstant, Jitter: true}).Delay(1))
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// contextDataEnvelope формат хранения контекстных данных модели в транзакции,
+// версия модели позволяет определить схему, по которой данные были записаны
+type contextDataEnvelope struct {
+	Version string          `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// WithContextData создание транзакции с начальными контекстными данными модели
+func WithContextData(data interface{}) CreateTxOption {
+	return func(o *CreateTxOptions) {
+		o.ContextData = data
+	}
+}
+
+// EncodeContextData кодирует контекстные данные модели для хранения в транзакции (Tx.SetContextData)
+func EncodeContextData(version string, data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("context data marshal: %w", err)
+	}
+
+	return json.Marshal(&contextDataEnvelope{
+		Version: version,
+		Data:    raw,
+	})
+}
+
+// DecodeContextData декодирует хранимые в транзакции контекстные данные в data,
+// возвращает версию модели, в которой данные были записаны
+func DecodeContextData(stored []byte, data interface{}) (string, error) {
+	envelope := &contextDataEnvelope{}
+
+	err := json.Unmarshal(stored, envelope)
+	if err != nil {
+		return "", fmt.Errorf("context data envelope unmarshal: %w", err)
+	}
+
+	if len(envelope.Data) == 0 {
+		return envelope.Version, nil
+	}
+
+	err = json.Unmarshal(envelope.Data, data)
+	if err != nil {
+		return envelope.Version, fmt.Errorf("context data unmarshal: %w", err)
+	}
+
+	return envelope.Version, nil
+}
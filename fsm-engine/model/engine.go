@@ -16,10 +16,15 @@ type Engine interface {
 	Stop(ctx context.Context)
 	// AddModel инициализирует очередную fsm модель
 	AddModel(ctx context.Context, newModel Model) error
-	// Resolve ищем состояние по названию среди инициализированных моделей, либо nil
+	// Resolve ищем состояние по названию среди последних версий инициализированных моделей, либо nil
 	Resolve(ctx context.Context, state string) (State, Model)
-	// CreateTx задает транзакции начальное состояние и отправляет событие на его обработку
-	CreateTx(ctx context.Context, tx Tx, initState State) error
+	// ResolveVersion ищем состояние по названию в указанной версии модели, либо nil
+	ResolveVersion(ctx context.Context, state string, version string) (State, Model)
+	// RetireVersions выводит из эксплуатации устаревшие версии моделей, на которые не ссылается ни одна транзакция
+	RetireVersions(ctx context.Context) error
+	// CreateTx задает транзакции начальное состояние и отправляет событие на его обработку,
+	// возвращает сохраненную транзакцию (ранее созданную, если повторно передан ключ идемпотентности)
+	CreateTx(ctx context.Context, tx Tx, initState State, opts ...CreateTxOption) (Tx, error)
 	// Transit создает событие на проведение транзакции из одного состояния в другое
 	Transit(ctx context.Context, tx Tx, newState State) error
 }
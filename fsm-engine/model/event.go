@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"fsm-framework/misk/prettyuuid"
@@ -49,10 +50,105 @@ type Event struct {
 	FinalState string      `json:"final_state" db:"final_state"`
 	Status     EventStatus `json:"status" db:"status"`
 	// RetryN попытки, отсчет с 0
-	RetryN  int       `json:"retry_n" db:"retry_n"`
-	SpanID  string    `json:"span_id" db:"span_id"`
-	Updated time.Time `json:"updated" db:"updated"`
-	Created time.Time `json:"created" db:"created"`
+	RetryN int    `json:"retry_n" db:"retry_n"`
+	SpanID string `json:"span_id" db:"span_id"`
+	// ModelVersion версия модели, по которой обрабатывается событие
+	ModelVersion string `json:"model_version" db:"model_version"`
+	// CompensatedState состояние, компенсирующий обработчик которого исполняется в рамках события (saga),
+	// пусто для обычных событий
+	CompensatedState string `json:"compensated_state,omitempty" db:"compensated_state"`
+	// Compensations очередь состояний, ожидающих компенсации после CompensatedState (в порядке исполнения)
+	Compensations []string `json:"compensations,omitempty" db:"compensations"`
+	// Data декодированные контекстные данные модели (Model.NewContextData), сохраняются в транзакции
+	Data    interface{} `json:"-" db:"-"`
+	Updated time.Time   `json:"updated" db:"updated"`
+	Created time.Time   `json:"created" db:"created"`
+}
+
+// IsCompensation событие исполняет компенсирующий обработчик, а не обработчик состояния
+func (e *Event) IsCompensation() bool {
+	return e.CompensatedState != ""
+}
+
+// eventJSON формат события в очереди: помимо самой транзакции передается ее tx_id,
+// так как интерфейс Tx не может быть декодирован обратно. Формат расширяет прежний (поле tx не изменилось),
+// сообщения без tx_id от предыдущих релизов декодируются через legacyTxID
+type eventJSON struct {
+	eventAlias
+	TxID uuid.UUID       `json:"tx_id"`
+	Tx   json.RawMessage `json:"tx"`
+}
+
+type eventAlias Event
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	v := eventJSON{
+		eventAlias: eventAlias(e),
+	}
+
+	if e.Tx != nil {
+		tx, err := json.Marshal(e.Tx)
+		if err != nil {
+			return nil, err
+		}
+
+		v.TxID = e.Tx.ID()
+		v.Tx = tx
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON восстанавливает из транзакции только tx_id (TxRef),
+// актуальная транзакция загружается из репозитория при обработке события
+func (e *Event) UnmarshalJSON(data []byte) error {
+	v := eventJSON{}
+
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+
+	*e = Event(v.eventAlias)
+
+	txID := v.TxID
+	if txID == uuid.Nil {
+		txID = legacyTxID(v.Tx)
+	}
+
+	e.Tx = TxRef(txID)
+
+	return nil
+}
+
+// legacyTxIDKeys названия поля идентификатора в json транзакции сервиса (без учета регистра)
+var legacyTxIDKeys = []string{"tx_id", "txid", "id"}
+
+// legacyTxID ищет идентификатор транзакции в json транзакции сервиса (события предыдущих релизов без tx_id),
+// uuid.Nil – если не найден
+func legacyTxID(tx json.RawMessage) uuid.UUID {
+	fields := map[string]json.RawMessage{}
+
+	err := json.Unmarshal(tx, &fields)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	for _, key := range legacyTxIDKeys {
+		for name, value := range fields {
+			if !strings.EqualFold(name, key) {
+				continue
+			}
+
+			var id uuid.UUID
+
+			if json.Unmarshal(value, &id) == nil && id != uuid.Nil {
+				return id
+			}
+		}
+	}
+
+	return uuid.Nil
 }
 
 func EventMarshal(e *Event) ([]byte, error) {
@@ -71,19 +167,46 @@ func EventUnmarshal(body []byte) (*Event, error) {
 }
 
 func NewEvent(state State, tx Tx, retryN int) *Event {
+	return NewEventAt(state, tx, retryN, time.Now())
+}
+
+// NewEventAt создает событие с указанным временем создания (часы движка)
+func NewEventAt(state State, tx Tx, retryN int, now time.Time) *Event {
 	// creates ID in form of EE00xxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx for easy debugging
 	UUID := prettyuuid.New(0xEE, 0x00)
 
 	return &Event{
-		ID:         UUID,
-		Tx:         tx,
-		Type:       state.EventType(),
-		StartState: state.Name(),
-		FinalState: "",
-		Status:     EventStatusPending,
-		RetryN:     retryN,
-		SpanID:     "",
-		Updated:    time.Now(),
-		Created:    time.Now(),
+		ID:           UUID,
+		Tx:           tx,
+		Type:         state.EventType(),
+		StartState:   state.Name(),
+		ModelVersion: state.Model().Version(),
+		FinalState:   "",
+		Status:       EventStatusPending,
+		RetryN:       retryN,
+		SpanID:       "",
+		Updated:      now,
+		Created:      now,
 	}
 }
+
+var _ Tx = TxRef{}
+
+// TxRef ссылка на транзакцию по tx_id в декодированном из очереди событии, остальные поля пусты
+type TxRef uuid.UUID
+
+func (t TxRef) ID() uuid.UUID              { return uuid.UUID(t) }
+func (t TxRef) State() State               { return nil }
+func (t TxRef) SetState(State)             {}
+func (t TxRef) Status() TxStatus           { return "" }
+func (t TxRef) SetStatus(TxStatus)         {}
+func (t TxRef) TraceID() string            { return "" }
+func (t TxRef) SetTraceID(string)          {}
+func (t TxRef) SpanID() string             { return "" }
+func (t TxRef) SetSpanID(string)           {}
+func (t TxRef) CallbackURL() string        { return "" }
+func (t TxRef) SetCallbackURL(string)      {}
+func (t TxRef) ModelVersion() string       { return "" }
+func (t TxRef) SetModelVersion(string)     {}
+func (t TxRef) ContextData() []byte        { return nil }
+func (t TxRef) SetContextData(data []byte) {}
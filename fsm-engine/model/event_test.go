@@ -0,0 +1,109 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serviceTx транзакция сервиса в том виде, в каком она сериализуется в событие
+type serviceTx struct {
+	TxRef  `json:"-"`
+	TxID   uuid.UUID `json:"TxID"`
+	Amount int64     `json:"amount"`
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	txID := uuid.New()
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	ev := &Event{
+		Tx:               &serviceTx{TxRef: TxRef(txID), TxID: txID, Amount: 100},
+		ID:               uuid.New(),
+		Type:             "first_created_event",
+		StartState:       "CREATED",
+		Status:           EventStatusRetry,
+		RetryN:           2,
+		SpanID:           "span",
+		ModelVersion:     "v1",
+		CompensatedState: "RESERVE",
+		Compensations:    []string{"CREATED"},
+		Updated:          created,
+		Created:          created,
+	}
+
+	body, err := EventMarshal(ev)
+	require.NoError(t, err)
+
+	// транзакция сериализуется как раньше, tx_id добавлен рядом
+	fields := map[string]json.RawMessage{}
+	require.NoError(t, json.Unmarshal(body, &fields))
+	assert.JSONEq(t, `"`+txID.String()+`"`, string(fields["tx_id"]))
+	assert.JSONEq(t, `{"TxID":"`+txID.String()+`","amount":100}`, string(fields["tx"]))
+
+	decoded, err := EventUnmarshal(body)
+	require.NoError(t, err)
+
+	assert.Equal(t, TxRef(txID), decoded.Tx)
+
+	decoded.Tx = ev.Tx
+	assert.Equal(t, ev, decoded)
+}
+
+func TestEventUnmarshalLegacy(t *testing.T) {
+	txID := uuid.New()
+
+	tests := []struct {
+		name string
+		tx   string
+		want uuid.UUID
+	}{
+		{
+			name: "service field name",
+			tx:   `{"TxID":"` + txID.String() + `","TxState":"CREATED"}`,
+			want: txID,
+		},
+		{
+			name: "snake case",
+			tx:   `{"tx_id":"` + txID.String() + `"}`,
+			want: txID,
+		},
+		{
+			name: "id",
+			tx:   `{"id":"` + txID.String() + `"}`,
+			want: txID,
+		},
+		{
+			name: "tx_id preferred over id",
+			tx:   `{"id":"` + uuid.New().String() + `","tx_id":"` + txID.String() + `"}`,
+			want: txID,
+		},
+		{
+			name: "no id",
+			tx:   `{"amount":100}`,
+			want: uuid.Nil,
+		},
+		{
+			name: "not an object",
+			tx:   `null`,
+			want: uuid.Nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// событие предыдущего релиза: без tx_id верхнего уровня
+			body := `{"tx":` + tt.tx + `,"event_id":"` + uuid.New().String() + `","start_state":"CREATED"}`
+
+			ev, err := EventUnmarshal([]byte(body))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, ev.Tx.ID())
+			assert.Equal(t, "CREATED", ev.StartState)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package model
 
 type Model interface {
 	Name() string
+	// Version ревизия модели (ETag), несколько версий одной модели могут работать в движке одновременно
+	Version() string
 	States() []State
 	Resolve(name string) State
 	Has(state State) bool
@@ -9,4 +11,6 @@ type Model interface {
 	Engine() Engine
 	SetService(svc interface{}) error
 	Service() interface{}
+	// NewContextData создает пустую структуру контекстных данных модели, nil – если данные не декларированы
+	NewContextData() interface{}
 }
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRepository хранилище ключей идемпотентности создания транзакций,
+// опционально реализуется вместе с Repository
+type IdempotencyRepository interface {
+	// CreateTransactionIdempotent атомарно записывает новую транзакцию вместе с ключом идемпотентности,
+	// ключ хранится не менее retention. Если ключ уже сохранен, новая транзакция не записывается,
+	// а возвращается ранее созданная по этому ключу (created == false)
+	CreateTransactionIdempotent(ctx context.Context, tx Tx, key string, retention time.Duration) (
+		stored Tx, created bool, err error)
+}
+
+// CreateTxOptions параметры создания транзакции
+type CreateTxOptions struct {
+	// IdempotencyKey ключ идемпотентности, повторный вызов с тем же ключом вернет существующую транзакцию
+	IdempotencyKey string
+	// ContextData начальные контекстные данные модели
+	ContextData interface{}
+}
+
+// CreateTxOption задает параметр создания транзакции
+type CreateTxOption func(o *CreateTxOptions)
+
+// WithIdempotencyKey создание транзакции с ключом идемпотентности
+func WithIdempotencyKey(key string) CreateTxOption {
+	return func(o *CreateTxOptions) {
+		o.IdempotencyKey = key
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 type Repository interface {
 	// Transaction вычитывает данные о транзакции по её ID
+	// состояние транзакции следует разрешать с учетом версии модели (Engine.ResolveVersion)
 	Transaction(ctx context.Context, txID uuid.UUID) (Tx, error)
 	// UpdateTransaction обновляет данные о транзакции, если она существует и её текущий статус совпадает с currState
 	UpdateTransaction(ctx context.Context, tx Tx, currState string) error
@@ -17,3 +18,27 @@ type Repository interface {
 	// опционально, сейчас используется для аудита
 	UpdateEvent(ctx context.Context, event *Event) error
 }
+
+// EventRepository история событий транзакций, опционально реализуется вместе с Repository.
+// Необходима моделям с компенсирующими обработчиками (saga)
+type EventRepository interface {
+	// Events вычитывает историю событий транзакции, упорядоченную по времени создания
+	// используется для определения пройденного пути при компенсации
+	Events(ctx context.Context, txID uuid.UUID) ([]*Event, error)
+}
+
+// VersionRepository учет транзакций по версиям моделей, опционально реализуется вместе с Repository.
+// Необходим для вывода из эксплуатации старых версий модели (Engine.RetireVersions)
+type VersionRepository interface {
+	// CountActiveTransactions количество обрабатываемых (статус pending или progress) транзакций модели указанной версии.
+	// Транзакции в статусе error не учитываются: они не обрабатываются, пока не будут переведены вручную
+	CountActiveTransactions(ctx context.Context, modelName string, version string) (int, error)
+}
+
+// MigrationRepository поиск транзакций по состоянию, опционально реализуется вместе с Repository.
+// Необходим для миграции транзакций из удаленных состояний (Engine.Migrate)
+type MigrationRepository interface {
+	// TransactionsByState вычитывает транзакции, находящиеся в указанном состоянии
+	// состояние может отсутствовать в моделях движка, поэтому State() может вернуть nil
+	TransactionsByState(ctx context.Context, state string) ([]Tx, error)
+}
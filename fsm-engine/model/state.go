@@ -15,8 +15,39 @@ type State interface {
 	MinRetiesDelay() time.Duration
 	CancellationTTL() time.Duration
 	FallbackState() State
+	// RateLimit ограничение частоты обработки событий состояния для всех реплик, nil – без ограничений
+	RateLimit() *RateLimit
+	// Backoff стратегия задержки перед повторами, nil – постоянная задержка EventRetryMinDelay
+	Backoff() *Backoff
+	// CircuitBreaker приостановка обработки очереди состояния при серии неудач обработчика, nil – без приостановки
+	CircuitBreaker() *CircuitBreaker
 	IsInitial() bool
 	IsSuccessFinal() bool
 	IsFailFinal() bool
 	Model() Model
 }
+
+// Compensator компенсирующий обработчик состояния (saga).
+// Вызывается движком в обратном порядке для всех пройденных транзакцией состояний,
+// когда она попадает в конечное неудачное состояние (fail_final)
+type Compensator interface {
+	Compensate(ctx context.Context, ev *Event) error
+}
+
+// RateLimit ограничение частоты обработки событий состояния,
+// события сверх ограничения откладываются, а не повторяются с ошибкой
+type RateLimit struct {
+	// Rate количество событий в секунду
+	Rate float64
+	// Burst количество событий, которые могут быть обработаны единовременно
+	Burst int
+}
+
+// CircuitBreaker приостанавливает обработку очереди состояния после FailureThreshold неудач обработчика подряд,
+// спустя OpenTimeout пропускает одно пробное событие и при его успехе возобновляет обработку
+type CircuitBreaker struct {
+	// FailureThreshold количество неудач подряд (паника или повтор), после которого обработка приостанавливается
+	FailureThreshold int
+	// OpenTimeout время приостановки до пробного события
+	OpenTimeout time.Duration
+}
@@ -39,4 +39,12 @@ type Tx interface {
 
 	CallbackURL() string
 	SetCallbackURL(callbackURL string)
+
+	// ModelVersion версия модели, в рамках которой транзакция была создана и проводится до конца
+	ModelVersion() string
+	SetModelVersion(version string)
+
+	// ContextData хранимые контекстные данные модели (см. EncodeContextData), передаваемые между состояниями
+	ContextData() []byte
+	SetContextData(data []byte)
 }
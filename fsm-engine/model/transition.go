@@ -0,0 +1,48 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransitionRecordVersion версия формата записи о переходе, увеличивается при несовместимых изменениях
+const TransitionRecordVersion = 1
+
+// TransitionRecord запись о зафиксированном переходе транзакции между состояниями (CDC),
+// формат json стабилен в рамках TransitionRecordVersion
+type TransitionRecord struct {
+	// Version версия формата записи (TransitionRecordVersion)
+	Version int `json:"version"`
+	// ID идентификатор записи, уникален для перехода (для дедупликации): идентификатор события, завершившего переход,
+	// для ручных переходов и миграций – новый идентификатор
+	ID           uuid.UUID `json:"id"`
+	TxID         uuid.UUID `json:"tx_id"`
+	Model        string    `json:"model"`
+	ModelVersion string    `json:"model_version"`
+	FromState    string    `json:"from_state"`
+	// ToState новое состояние, пустое – если транзакция завершила обработку
+	ToState string `json:"to_state"`
+	// Final транзакция завершила обработку (конечное состояние или исчерпаны попытки без fallback)
+	Final    bool     `json:"final"`
+	TxStatus TxStatus `json:"tx_status"`
+	// TraceID, SpanID трейс транзакции и спан обработки события
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+	// TraceContext контекст трейсинга в формате text map (для продолжения трейса подписчиком)
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+	Created      time.Time         `json:"created"`
+}
+
+// TransitionRepository хранилище записей о переходах (outbox), опционально для реализаций Repository.
+// Запись сохраняется в одной транзакции БД с обновлением транзакции и удаляется только после того,
+// как брокер принял её публикацию, поэтому записи доставляются подписчикам хотя бы один раз
+type TransitionRepository interface {
+	// UpdateTransactionWithRecord обновляет транзакцию как UpdateTransaction и атомарно с этим сохраняет запись
+	UpdateTransactionWithRecord(ctx context.Context, tx Tx, currState string, record *TransitionRecord) error
+	// PendingTransitionRecords неопубликованные записи в порядке сохранения, не более limit
+	PendingTransitionRecords(ctx context.Context, limit int) ([]*TransitionRecord, error)
+	// DeleteTransitionRecords удаляет опубликованные записи
+	DeleteTransitionRecords(ctx context.Context, ids []uuid.UUID) error
+}
@@ -23,10 +23,24 @@ func (p *processPipeline) resolveNextState(pCtx context.Context) (context.Contex
 		defer span.Finish()
 	}
 
+	if p.event.IsCompensation() {
+		p.resolveNextCompensation()
+
+		return pCtx, nil
+	}
+
 	// если паники не было, состояние было успешно получено обработчика состояния
 	if !p.isPanicRecovered {
 		p.event.Status = model.EventStatusDone
 
+		// попав в конечное неудачное состояние, запускаем компенсацию пройденных состояний
+		if p.state.IsFailFinal() && p.nextState == nil {
+			err := p.planCompensations(pCtx)
+			if err != nil {
+				return pCtx, err
+			}
+		}
+
 		return pCtx, nil
 	}
 
@@ -37,6 +51,29 @@ func (p *processPipeline) resolveNextState(pCtx context.Context) (context.Contex
 	return pCtx, nil
 }
 
+// storeContextData кодирует измененные обработчиком контекстные данные модели в транзакцию
+func (p *processPipeline) storeContextData(pCtx context.Context) (context.Context, error) {
+	// при ошибке обработчика данные могли быть изменены частично, не сохраняем их
+	if p.event.Data == nil || p.isPanicRecovered || p.compensationErr != nil {
+		return pCtx, nil
+	}
+
+	data, err := model.EncodeContextData(p.state.Model().Version(), p.event.Data)
+	if err != nil {
+		p.span.SetTag("error", true).
+			LogFields(log.Message("can't encode tx context data"), log.Error(err))
+		zlog.Ctx(pCtx).Error().Err(err).Msg("can't encode tx context data")
+
+		p.delivery.Reject(pCtx)
+
+		return pCtx, fmt.Errorf("tx context data: %w", err)
+	}
+
+	p.event.Tx.SetContextData(data)
+
+	return pCtx, nil
+}
+
 // nextEvent создает следующее событие, если необходимо, иначе помечает транзакцию как завершенную
 func (p *processPipeline) nextEvent(pCtx context.Context) (context.Context, error) {
 	var (
@@ -55,14 +92,16 @@ func (p *processPipeline) nextEvent(pCtx context.Context) (context.Context, erro
 		return pCtx, nil
 	}
 
-	p.event.FinalState = p.nextState.Name()
-	p.event.Tx.SetState(p.nextState)
 	p.event.Tx.SetStatus(model.TxStatusPending)
 
-	// отсчет с 0
+	// отсчет с 0, неудача пробного события circuit breaker не расходует попытки транзакции
 	var retryN int
-	if p.nextState == p.state {
+	if p.isRetry() {
 		retryN = p.event.RetryN + 1
+
+		if p.breakerProbe {
+			retryN = p.event.RetryN
+		}
 	}
 
 	// если это была последняя попытка
@@ -70,17 +109,27 @@ func (p *processPipeline) nextEvent(pCtx context.Context) (context.Context, erro
 		p.event.Status = model.EventStatusError
 		p.event.Tx.SetStatus(model.TxStatusError)
 
-		if fallbackState := p.state.FallbackState(); fallbackState != nil {
-			p.nextState = fallbackState
-		} else {
-			p.nextState = nil
-		}
+		p.nextState = p.state.FallbackState()
+		p.nextCompensations = nil
+		retryN = 0
 
 		zlog.Ctx(ctx).Error().Msg("max retry count exceeded")
 		p.span.SetTag("error", true).LogFields(log.Message("max retry count exceeded"))
+
+		// переходить некуда, транзакция остается в текущем состоянии с ошибкой
+		if p.nextState == nil {
+			return pCtx, nil
+		}
 	}
 
-	nextEvent := model.NewEvent(p.nextState, p.event.Tx, retryN)
+	p.event.FinalState = p.nextState.Name()
+	p.event.Tx.SetState(p.nextState)
+
+	nextEvent := model.NewEventAt(p.nextState, p.event.Tx, retryN, p.cfg.clock.Now())
+	if len(p.nextCompensations) > 0 {
+		nextEvent.CompensatedState = p.nextCompensations[0]
+		nextEvent.Compensations = p.nextCompensations[1:]
+	}
 
 	p.span.LogFields(
 		log.String("next_state", p.nextState.Name()),
@@ -155,7 +204,17 @@ func (p *processPipeline) updateCompletedTx(pCtx context.Context) (context.Conte
 		defer span.Finish()
 	}
 
-	err := p.cfg.repo.UpdateTransaction(ctx, p.event.Tx, p.state.Name())
+	// запись о переходе сохраняется атомарно с транзакцией, поэтому не теряется при остановке реплики
+	var err error
+	if record := p.transitionRecord(ctx); record != nil {
+		err = p.cfg.transitionRepo.UpdateTransactionWithRecord(ctx, p.event.Tx, p.state.Name(), record)
+		if err == nil {
+			p.cfg.transitions.Notify()
+		}
+	} else {
+		err = p.cfg.repo.UpdateTransaction(ctx, p.event.Tx, p.state.Name())
+	}
+
 	if err != nil {
 		p.span.SetTag("error", true).
 			LogFields(log.String("msg", "can't update transaction status"), log.Error(err))
@@ -179,6 +238,11 @@ func (p *processPipeline) sendCallback(pCtx context.Context) (context.Context, e
 		return pCtx, nil
 	}
 
+	// компенсации еще не завершены, уведомление будет отправлено по их окончании
+	if p.nextState != nil {
+		return pCtx, nil
+	}
+
 	ctx := pCtx
 	if p.cfg.verboseTracing {
 		span, ctx = opentracing.StartSpanFromContext(pCtx, caller.CurrentFuncNameClear())
@@ -189,3 +253,80 @@ func (p *processPipeline) sendCallback(pCtx context.Context) (context.Context, e
 
 	return pCtx, nil
 }
+
+// transitionRecord запись о переходе транзакции, сохраняемая вместе с ней для публикации (TransitionPublisher),
+// nil – если публикация не настроена. Повторы и промежуточные шаги компенсации переходом не считаются
+func (p *processPipeline) transitionRecord(ctx context.Context) *model.TransitionRecord {
+	if p.cfg.transitions == nil || p.nextState == p.state {
+		return nil
+	}
+
+	record := &model.TransitionRecord{
+		Version:      model.TransitionRecordVersion,
+		ID:           p.event.ID,
+		TxID:         p.event.Tx.ID(),
+		Model:        p.state.Model().Name(),
+		ModelVersion: p.event.Tx.ModelVersion(),
+		FromState:    p.state.Name(),
+		Final:        p.nextState == nil,
+		TxStatus:     p.event.Tx.Status(),
+		TraceID:      p.event.Tx.TraceID(),
+		SpanID:       p.event.SpanID,
+		TraceContext: map[string]string{},
+		Created:      p.cfg.clock.Now(),
+	}
+
+	if p.nextState != nil {
+		record.ToState = p.nextState.Name()
+	}
+
+	err := p.span.Tracer().Inject(p.span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(record.TraceContext))
+	if err != nil {
+		zlog.Ctx(ctx).Warn().Err(err).Msg("can't inject trace context into transition record")
+	}
+
+	return record
+}
+
+// recordCircuitBreaker учитывает результат обработчика в circuit breaker очереди
+func (p *processPipeline) recordCircuitBreaker(ctx context.Context) {
+	cb := p.processor.circuitBreaker()
+	if cb == nil || p.event.IsCompensation() {
+		return
+	}
+
+	p.breakerRecorded = true
+
+	if !cb.record(p.cfg.clock.Now(), p.isRetry(), p.breakerProbe) {
+		return
+	}
+
+	status := p.notifyCircuitBreaker(ctx, cb)
+
+	p.span.LogFields(log.String("circuit_breaker", string(status.State)))
+}
+
+// notifyCircuitBreaker сообщает об изменении состояния circuit breaker очереди в лог и CircuitBreakerHook
+func (p *processPipeline) notifyCircuitBreaker(ctx context.Context, cb *circuitBreaker) CircuitBreakerStatus {
+	status := cb.status(p.processor.queue)
+
+	zlog.Ctx(ctx).Warn().
+		Str("circuit_breaker", string(status.State)).
+		Int("failures", status.Failures).
+		Msg("circuit breaker state changed")
+
+	if p.cfg.breakerHook != nil {
+		p.cfg.breakerHook(ctx, status)
+	}
+
+	return status
+}
+
+// isRetry следующее событие является повтором текущего
+func (p *processPipeline) isRetry() bool {
+	if p.event.Status == model.EventStatusRetry {
+		return true
+	}
+
+	return p.nextState == p.state && len(p.nextCompensations) == 0
+}
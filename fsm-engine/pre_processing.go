@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/opentracing/opentracing-go"
@@ -51,25 +50,107 @@ func (p *processPipeline) eventUnmarshal(ctx context.Context) (context.Context,
 	return ctx, nil
 }
 
-// checkRetryDelay спит, если с момента создания события прошло меньше model.EventRetryMinDelay
+// resolveState определяет состояние, соответствующее версии модели события
+func (p *processPipeline) resolveState(ctx context.Context) (context.Context, error) {
+	p.state = p.processor.State(p.event.ModelVersion)
+	if p.state == nil {
+		zlog.Ctx(ctx).Error().Str("model_version", p.event.ModelVersion).Msg("model version not initialized")
+
+		p.delivery.Ack(ctx)
+
+		return ctx, fmt.Errorf("model version %s not initialized", p.event.ModelVersion)
+	}
+
+	ctx = zlog.FromLogger(zlog.Ctx(ctx).With().
+		Str("state", p.state.Name()).
+		Str("model_version", p.state.Model().Version()).Logger()).WithContext(ctx)
+
+	return ctx, nil
+}
+
+// checkRetryDelay спит, если с момента создания события прошло меньше задержки повтора (State.Backoff)
 func (p *processPipeline) checkRetryDelay(pCtx context.Context) (context.Context, error) {
 	if p.event.RetryN == 0 {
 		return pCtx, nil
 	}
 
-	since := time.Since(p.event.Created)
-	diff := model.EventRetryMinDelay - since
+	delay := model.EventRetryMinDelay
+	if backoff := p.state.Backoff(); backoff != nil {
+		delay = backoff.Delay(p.event.RetryN)
+	}
+
+	since := p.cfg.clock.Since(p.event.Created)
+	diff := delay - since
 
 	if diff <= 0 {
 		return pCtx, nil
 	}
 
 	zlog.Ctx(pCtx).Trace().Dur("duration", diff).Msg("sleep for delaying event processing")
-	time.Sleep(diff)
+	p.cfg.clock.Sleep(diff)
 
 	return pCtx, nil
 }
 
+// checkCircuitBreaker приостанавливает обработку очереди, пока circuit breaker состояния открыт:
+// событие возвращается в очередь (не расходуя попытки транзакции), а консюмер очереди останавливается
+// до пробного события, чтобы не удерживать неподтвержденные сообщения дольше таймаутов брокера.
+// Компенсации не приостанавливаются, так как исполняют другие обработчики
+func (p *processPipeline) checkCircuitBreaker(ctx context.Context) (context.Context, error) {
+	cb := p.processor.circuitBreaker()
+	if cb == nil || p.event.IsCompensation() {
+		return ctx, nil
+	}
+
+	wait, probe, changed := cb.allow(p.cfg.clock.Now())
+	if changed {
+		p.notifyCircuitBreaker(ctx, cb)
+	}
+
+	if wait <= 0 {
+		p.breakerProbe = probe
+
+		if probe {
+			zlog.Ctx(ctx).Info().Msg("circuit breaker probe event")
+		}
+
+		return ctx, nil
+	}
+
+	zlog.Ctx(ctx).Debug().Dur("duration", wait).Msg("consumer paused while circuit breaker is open")
+
+	p.delivery.Reject(ctx)
+	p.processor.pause(wait)
+
+	return ctx, errors.New("circuit breaker is open")
+}
+
+// waitRateLimit откладывает обработку события, если превышено ограничение частоты состояния.
+// Ожидание происходит до взятия лока, событие не считается повтором
+func (p *processPipeline) waitRateLimit(ctx context.Context) (context.Context, error) {
+	limit := p.state.RateLimit()
+	if limit == nil || p.cfg.limiter == nil {
+		return ctx, nil
+	}
+
+	wait, err := p.cfg.limiter.Reserve(ctx, p.state.Name(), limit)
+	if err != nil {
+		zlog.Ctx(ctx).Error().Err(err).Msg("rate limit reservation failed")
+		p.delivery.Reject(ctx)
+
+		return ctx, err
+	}
+
+	if wait <= 0 {
+		return ctx, nil
+	}
+
+	zlog.Ctx(ctx).Debug().Dur("duration", wait).Msg("sleep for state rate limit")
+	p.cfg.clock.Sleep(wait)
+
+	return ctx, nil
+}
+
 // obtainLock попытка заполучить эксклюзивную блокировку на обработку события
 func (p *processPipeline) obtainLock(ctx context.Context) (context.Context, error) {
 	var err error
@@ -78,7 +159,7 @@ func (p *processPipeline) obtainLock(ctx context.Context) (context.Context, erro
 	if err != nil {
 		if p.cfg.locker.IsErrNotObtained(err) {
 			zlog.Ctx(ctx).Debug().Err(err).Msg("lock already obtained by other consumer")
-			time.Sleep(model.EventRetryMinDelay) // жесткий фикс ретраев для локов
+			p.cfg.clock.Sleep(model.EventRetryMinDelay) // жесткий фикс ретраев для локов
 		} else {
 			err = fmt.Errorf("lock obtaining error: %w", err)
 			zlog.Ctx(ctx).Error().Err(err).Msg("consumer lock obtain failed")
@@ -117,6 +198,56 @@ func (p *processPipeline) checkTx(ctx context.Context) (context.Context, error)
 	return ctx, nil
 }
 
+// loadContextData декодирует хранимые в транзакции контекстные данные модели для обработчика
+func (p *processPipeline) loadContextData(ctx context.Context) (context.Context, error) {
+	data := p.state.Model().NewContextData()
+	if data == nil {
+		return ctx, nil
+	}
+
+	if stored := p.event.Tx.ContextData(); len(stored) > 0 {
+		version, err := model.DecodeContextData(stored, data)
+		if err != nil {
+			zlog.Ctx(ctx).Error().Err(err).Msg("can't decode tx context data")
+
+			p.failTx(ctx)
+
+			return ctx, fmt.Errorf("tx context data: %w", err)
+		}
+
+		if version != p.state.Model().Version() {
+			zlog.Ctx(ctx).Debug().Str("data_version", version).Msg("tx context data written by other model version")
+		}
+	}
+
+	p.event.Data = data
+
+	return ctx, nil
+}
+
+// failTx переводит транзакцию и событие в статус ошибки, если обработка события невозможна при любой попытке,
+// событие подтверждается только после сохранения статуса, иначе повторяется
+func (p *processPipeline) failTx(ctx context.Context) {
+	p.event.Status = model.EventStatusError
+	p.event.Tx.SetStatus(model.TxStatusError)
+
+	err := p.cfg.repo.UpdateEvent(ctx, p.event)
+	if err != nil {
+		zlog.Ctx(ctx).Warn().Err(err).Msg("event update error")
+	}
+
+	err = p.cfg.repo.UpdateTransaction(ctx, p.event.Tx, p.event.Tx.State().Name())
+	if err != nil {
+		zlog.Ctx(ctx).Error().Err(err).Msg("tx status update error")
+
+		p.delivery.Reject(ctx)
+
+		return
+	}
+
+	p.delivery.Ack(ctx)
+}
+
 // startTracing создает новый span для текущей обработки события
 func (p *processPipeline) startTracing(ctx context.Context) (context.Context, error) {
 	var parentSpanCtx opentracing.SpanContext
@@ -8,9 +8,11 @@ import (
 	"github.com/opentracing/opentracing-go/log"
 
 	callback_manager "fsm-framework/fsm-engine/callback-manager"
+	"fsm-framework/fsm-engine/clock"
 	"fsm-framework/fsm-engine/lock"
 	"fsm-framework/fsm-engine/model"
 	"fsm-framework/fsm-engine/queue"
+	"fsm-framework/fsm-engine/ratelimit"
 	zlog "fsm-framework/misk/logger"
 )
 
@@ -23,8 +25,20 @@ type pipelineConfig struct {
 	ch queue.Channel
 	// locker объект для получения блокировок на обработку транзакций
 	locker lock.Locker
+	// limiter распределенное ограничение частоты обработки состояний (может быть nil)
+	limiter ratelimit.Limiter
+	// transitions публикация записей о переходах транзакций (может быть nil)
+	transitions *TransitionPublisher
+	// transitionRepo outbox записей о переходах в репозитории repo (nil, если публикация не настроена)
+	transitionRepo model.TransitionRepository
 	// verboseTracing подробное логгирование в трейсинг
 	verboseTracing bool
+	// clock источник времени для задержек обработки
+	clock clock.Clock
+	// interceptor обертка вызова обработчиков состояний (может быть nil)
+	interceptor HandlerInterceptor
+	// breakerHook получает изменения состояний circuit breaker (может быть nil)
+	breakerHook CircuitBreakerHook
 }
 
 // processPipeline структура проводящая процесс пре/постобработки конкретного полученного из очереди сообщения
@@ -32,7 +46,9 @@ type processPipeline struct {
 	// cfg зависимости для обработки транзакции
 	cfg *pipelineConfig
 
-	// state текущее состояние
+	// processor обработчик очереди, из которой получено сообщение
+	processor *StateProcessor
+	// state текущее состояние (в версии модели события)
 	state model.State
 	// delivery исходная посылка, полученная из очереди
 	delivery queue.Delivery
@@ -49,12 +65,20 @@ type processPipeline struct {
 	nextState model.State
 	// nextStateMessage сообщение, которое нужно отправить в очередь (может быть nil)
 	nextStateMessage []byte
+	// compensationErr ошибка компенсирующего обработчика (saga)
+	compensationErr error
+	// breakerProbe событие пропущено circuit breaker как пробное
+	breakerProbe bool
+	// breakerRecorded результат обработчика учтен в circuit breaker
+	breakerRecorded bool
+	// nextCompensations очередь состояний для компенсации, передаваемая в следующее событие (может быть nil)
+	nextCompensations []string
 }
 
-func newProcessPipeline(cfg *pipelineConfig, state model.State) *processPipeline {
+func newProcessPipeline(cfg *pipelineConfig, processor *StateProcessor) *processPipeline {
 	return &processPipeline{
-		cfg:   cfg,
-		state: state,
+		cfg:       cfg,
+		processor: processor,
 	}
 }
 
@@ -75,6 +99,11 @@ func (p *processPipeline) Process(ctx context.Context, delivery queue.Delivery)
 		return
 	}
 
+	ctx, err = p.resolveState(ctx)
+	if err != nil {
+		return
+	}
+
 	zlog.Ctx(ctx).Trace().Msg("event processing")
 
 	ctx, err = p.checkRetryDelay(ctx)
@@ -84,6 +113,20 @@ func (p *processPipeline) Process(ctx context.Context, delivery queue.Delivery)
 
 	zlog.Ctx(ctx).Trace().Msg("tx event was delayed if needed")
 
+	ctx, err = p.checkCircuitBreaker(ctx)
+	if err != nil {
+		return
+	}
+
+	zlog.Ctx(ctx).Trace().Msg("state circuit breaker passed")
+
+	ctx, err = p.waitRateLimit(ctx)
+	if err != nil {
+		return
+	}
+
+	zlog.Ctx(ctx).Trace().Msg("state rate limit passed")
+
 	ctx, err = p.obtainLock(ctx)
 	if err != nil {
 		return
@@ -98,6 +141,13 @@ func (p *processPipeline) Process(ctx context.Context, delivery queue.Delivery)
 
 	zlog.Ctx(ctx).Trace().Msg("tx state validated")
 
+	ctx, err = p.loadContextData(ctx)
+	if err != nil {
+		return
+	}
+
+	zlog.Ctx(ctx).Trace().Msg("context data loaded")
+
 	ctx, err = p.startTracing(ctx)
 	if err != nil {
 		return
@@ -132,6 +182,15 @@ func (p *processPipeline) Process(ctx context.Context, delivery queue.Delivery)
 
 	zlog.Ctx(ctx).Trace().Msg("resolved next state successfully")
 
+	p.recordCircuitBreaker(ctx)
+
+	ctx, err = p.storeContextData(ctx)
+	if err != nil {
+		return
+	}
+
+	zlog.Ctx(ctx).Trace().Msg("context data stored")
+
 	ctx, err = p.nextEvent(ctx)
 	if err != nil {
 		return
@@ -186,6 +245,13 @@ func (p *processPipeline) stop(ctx context.Context) {
 		p.cfg.ch.Publish(ctx, p.nextState.Queue(), p.nextStateMessage)
 	}
 
+	// пробное событие не дошло до обработчика
+	if p.breakerProbe && !p.breakerRecorded {
+		if cb := p.processor.circuitBreaker(); cb != nil {
+			cb.cancelProbe()
+		}
+	}
+
 	// stop span
 	if p.span != nil {
 		p.span.Finish()
@@ -207,10 +273,24 @@ func (p *processPipeline) process(ctx context.Context) {
 		}
 	}()
 
+	if p.event.IsCompensation() {
+		p.compensate(ctx)
+
+		return
+	}
+
 	sp, handlerCtx := opentracing.StartSpanFromContext(ctx, "Event Handler")
 	defer sp.Finish()
 
-	p.nextState = p.state.EventHandler(handlerCtx, p.event)
+	handler := p.state.EventHandler
+	if p.cfg.interceptor != nil {
+		state, next := p.state, handler
+		handler = func(ctx context.Context, ev *model.Event) model.State {
+			return p.cfg.interceptor(ctx, state, ev, next)
+		}
+	}
+
+	p.nextState = handler(handlerCtx, p.event)
 	if p.nextState != nil {
 		p.span.LogFields(log.String("next_state", p.nextState.Name()))
 	}
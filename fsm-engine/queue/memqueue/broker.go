@@ -0,0 +1,301 @@
+package memqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/queue"
+)
+
+var _ queue.Broker = &Broker{}
+
+// Broker брокер сообщений в памяти процесса для тестов и локальной разработки.
+// Повторяет семантику rabbit: очереди с конкурирующими консюмерами, ack/reject с повторной доставкой,
+// неподтвержденные сообщения возвращаются в очередь при закрытии канала
+type Broker struct {
+	// m защищает очереди и состояние каналов, cond оповещает консюмеров о новых сообщениях
+	m    sync.Mutex
+	cond *sync.Cond
+
+	queues   map[string]*memQueue
+	channels []*Channel
+	opts     options
+}
+
+type Option func(*options)
+
+type options struct {
+	// deliveryDelay задержка, спустя которую опубликованное сообщение становится доступным консюмерам
+	deliveryDelay time.Duration
+	// redeliveryDelay задержка возврата отклоненного сообщения в очередь
+	redeliveryDelay time.Duration
+	// clock источник времени задержек
+	clock clock.Clock
+}
+
+// WithDeliveryDelay имитирует задержку доставки опубликованных сообщений
+func WithDeliveryDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.deliveryDelay = d
+	}
+}
+
+// WithRedeliveryDelay имитирует задержку повторной доставки отклоненных (Reject) сообщений
+func WithRedeliveryDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.redeliveryDelay = d
+	}
+}
+
+// WithClock источник времени для задержек доставки (по умолчанию системное время),
+// с clock.Fake отложенные сообщения становятся доступны при сдвиге часов
+func WithClock(clk clock.Clock) Option {
+	return func(o *options) {
+		o.clock = clk
+	}
+}
+
+// QueueStats состояние очереди
+type QueueStats struct {
+	// Ready сообщения, ожидающие доставки
+	Ready int
+	// Unacked доставленные, но еще не подтвержденные сообщения
+	Unacked int
+	// Delayed сообщения, доставка которых отложена (WithDeliveryDelay, WithRedeliveryDelay)
+	Delayed int
+	// Published, Delivered, Redelivered счетчики за все время работы очереди
+	Published   int
+	Delivered   int
+	Redelivered int
+}
+
+type message struct {
+	body        []byte
+	redelivered bool
+	// readyAt время, с которого отложенное сообщение доступно консюмерам
+	readyAt time.Time
+}
+
+type memQueue struct {
+	ready []*message
+	// delayed отложенные сообщения по возрастанию readyAt, timer срабатывает на первое из них
+	delayed []*message
+	timer   clock.Timer
+	stats   QueueStats
+	// handling доставленные сообщения, обработчик которых еще выполняется (даже после ack)
+	handling int
+}
+
+func NewBroker(opts ...Option) *Broker {
+	b := &Broker{
+		queues: make(map[string]*memQueue),
+		opts: options{
+			clock: clock.Real{},
+		},
+	}
+	b.cond = sync.NewCond(&b.m)
+
+	for _, opt := range opts {
+		opt(&b.opts)
+	}
+
+	return b
+}
+
+func (b *Broker) Channel() (queue.Channel, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ch := &Channel{
+		broker:  b,
+		unacked: make(map[*Delivery]struct{}),
+	}
+	b.channels = append(b.channels, ch)
+
+	return ch, nil
+}
+
+func (b *Broker) Close(ctx context.Context) {
+	b.m.Lock()
+	channels := b.channels
+	b.channels = nil
+	b.m.Unlock()
+
+	for _, ch := range channels {
+		_ = ch.Close()
+	}
+}
+
+// Stats состояние очереди (нулевое, если очередь не создана)
+func (b *Broker) Stats(queueName string) QueueStats {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	q, ok := b.queues[queueName]
+	if !ok {
+		return QueueStats{}
+	}
+
+	stats := q.stats
+	stats.Ready = len(q.ready)
+
+	return stats
+}
+
+// Idle во всех очередях нет сообщений, ожидающих доставки, обработки или отложенных,
+// и ни один обработчик не выполняется (обработчик может опубликовать новые сообщения после ack)
+func (b *Broker) Idle() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for _, q := range b.queues {
+		if len(q.ready) > 0 || q.stats.Unacked > 0 || q.stats.Delayed > 0 || q.handling > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NextDelayed время готовности первого отложенного сообщения, если в очередях нет других сообщений
+// и ни один обработчик не выполняется: дальнейшая обработка возможна только после сдвига часов (WithClock)
+func (b *Broker) NextDelayed() (time.Time, bool) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	var (
+		next  time.Time
+		found bool
+	)
+
+	for _, q := range b.queues {
+		if len(q.ready) > 0 || q.stats.Unacked > 0 || q.handling > 0 {
+			return time.Time{}, false
+		}
+
+		if len(q.delayed) > 0 && (!found || q.delayed[0].readyAt.Before(next)) {
+			next, found = q.delayed[0].readyAt, true
+		}
+	}
+
+	return next, found
+}
+
+// queue возвращает очередь, создавая ее при необходимости, вызывается под b.m
+func (b *Broker) queue(name string) *memQueue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &memQueue{}
+		b.queues[name] = q
+	}
+
+	return q
+}
+
+// push кладет сообщение в очередь (в начало – для повторной доставки), учитывая имитацию задержек
+func (b *Broker) push(name string, msg *message, delay time.Duration) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	q := b.queue(name)
+
+	if !msg.redelivered {
+		q.stats.Published++
+	}
+
+	if delay <= 0 {
+		b.enqueue(q, msg)
+
+		return
+	}
+
+	// отложенные сообщения упорядочены по времени готовности, чтобы задержка не меняла порядок доставки
+	msg.readyAt = b.opts.clock.Now().Add(delay)
+
+	i := len(q.delayed)
+	for i > 0 && q.delayed[i-1].readyAt.After(msg.readyAt) {
+		i--
+	}
+
+	q.delayed = append(q.delayed[:i], append([]*message{msg}, q.delayed[i:]...)...)
+	q.stats.Delayed = len(q.delayed)
+
+	if i == 0 {
+		b.scheduleDelayed(q)
+	}
+}
+
+// scheduleDelayed перезапускает таймер на время готовности первого отложенного сообщения, вызывается под b.m
+func (b *Broker) scheduleDelayed(q *memQueue) {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+
+	if len(q.delayed) == 0 {
+		return
+	}
+
+	clk := b.opts.clock
+
+	q.timer = clk.AfterFunc(q.delayed[0].readyAt.Sub(clk.Now()), func() {
+		b.m.Lock()
+		defer b.m.Unlock()
+
+		now := clk.Now()
+
+		for len(q.delayed) > 0 && !q.delayed[0].readyAt.After(now) {
+			msg := q.delayed[0]
+			q.delayed = q.delayed[1:]
+			b.enqueue(q, msg)
+		}
+
+		q.stats.Delayed = len(q.delayed)
+		q.timer = nil
+
+		b.scheduleDelayed(q)
+	})
+}
+
+// enqueue вызывается под b.m
+func (b *Broker) enqueue(q *memQueue, msg *message) {
+	if msg.redelivered {
+		q.ready = append([]*message{msg}, q.ready...)
+	} else {
+		q.ready = append(q.ready, msg)
+	}
+
+	b.cond.Broadcast()
+}
+
+// pop ожидает сообщение в очереди, пока канал не закрыт
+func (b *Broker) pop(name string, ch *Channel) (*message, bool) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for {
+		if ch.closed {
+			return nil, false
+		}
+
+		q := b.queue(name)
+		if len(q.ready) > 0 {
+			msg := q.ready[0]
+			q.ready = q.ready[1:]
+
+			q.stats.Unacked++
+			q.stats.Delivered++
+			q.handling++
+
+			if msg.redelivered {
+				q.stats.Redelivered++
+			}
+
+			return msg, true
+		}
+
+		b.cond.Wait()
+	}
+}
@@ -0,0 +1,86 @@
+package memqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/queue"
+	"fsm-framework/fsm-engine/queue/queuetest"
+)
+
+func TestBroker(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) queue.Broker {
+		return NewBroker()
+	})
+}
+
+func TestBrokerDelays(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) queue.Broker {
+		return NewBroker(WithDeliveryDelay(10*time.Millisecond), WithRedeliveryDelay(10*time.Millisecond))
+	})
+}
+
+func TestBrokerFakeClock(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	b := NewBroker(WithClock(clk), WithDeliveryDelay(time.Minute), WithRedeliveryDelay(time.Hour))
+
+	defer b.Close(ctx)
+
+	pub, err := b.Channel()
+	require.NoError(t, err)
+
+	pub.Publish(ctx, "q", []byte("msg"))
+
+	// сообщение отложено, пока часы не сдвинуты
+	readyAt, ok := b.NextDelayed()
+	require.True(t, ok)
+	assert.Equal(t, clk.Now().Add(time.Minute), readyAt)
+	assert.Equal(t, QueueStats{Delayed: 1, Published: 1}, b.Stats("q"))
+
+	clk.Advance(time.Minute - time.Second)
+	assert.Equal(t, 1, b.Stats("q").Delayed)
+
+	clk.Advance(time.Second)
+	assert.Equal(t, QueueStats{Ready: 1, Published: 1}, b.Stats("q"))
+
+	_, ok = b.NextDelayed()
+	assert.False(t, ok)
+
+	consumer, err := b.Channel()
+	require.NoError(t, err)
+
+	deliveries := make(chan queue.Delivery, 2)
+
+	err = consumer.Consume(ctx, "q", func(ctx context.Context, d queue.Delivery) error {
+		deliveries <- d
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// отклоненное сообщение возвращается в очередь только после сдвига часов на WithRedeliveryDelay
+	(<-deliveries).Reject(ctx)
+
+	require.Eventually(t, func() bool {
+		_, ok := b.NextDelayed()
+
+		return ok
+	}, queuetest.Timeout, time.Millisecond)
+
+	assert.Equal(t, 1, b.Stats("q").Delayed)
+
+	clk.Advance(time.Hour)
+
+	d := <-deliveries
+	d.Ack(ctx)
+
+	stats := b.Stats("q")
+	assert.Equal(t, 1, stats.Redelivered)
+	assert.Zero(t, stats.Delayed)
+}
@@ -0,0 +1,163 @@
+package memqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+var (
+	ErrConsumerExists = errors.New("consumer already exists")
+	ErrConsumerOnly   = errors.New("publish canceled, consumer-only or closed channel")
+)
+
+type Channel struct {
+	broker *Broker
+	// consuming канал уже используется для консюминга (канал может быть использован только для чего-то одного),
+	// closed канал закрыт, unacked неподтвержденные доставки канала; поля защищены broker.m
+	consuming bool
+	closed    bool
+	unacked   map[*Delivery]struct{}
+	// wg ожидание завершения обработчика текущего сообщения
+	wg sync.WaitGroup
+}
+
+func (c *Channel) Close() error {
+	c.broker.m.Lock()
+	c.closed = true
+	c.broker.cond.Broadcast()
+	c.broker.m.Unlock()
+
+	c.wg.Wait()
+
+	// как и в rabbit, неподтвержденные сообщения возвращаются в очередь при закрытии канала
+	c.broker.m.Lock()
+	unacked := c.unacked
+	c.unacked = make(map[*Delivery]struct{})
+	c.broker.m.Unlock()
+
+	for d := range unacked {
+		c.settle(d, true)
+	}
+
+	return nil
+}
+
+func (c *Channel) DeclareQueue(name string) error {
+	c.broker.m.Lock()
+	defer c.broker.m.Unlock()
+
+	c.broker.queue(name)
+
+	return nil
+}
+
+// Publish кладет сообщение в очередь, несуществующая очередь создается
+func (c *Channel) Publish(ctx context.Context, queueName string, body []byte) {
+	err := c.PublishConfirm(ctx, queueName, body)
+	if err != nil {
+		zlog.Ctx(ctx).Error().Err(err).Msg("memqueue publish error")
+	}
+}
+
+func (c *Channel) PublishConfirm(ctx context.Context, queueName string, body []byte) error {
+	c.broker.m.Lock()
+	consuming, closed := c.consuming, c.closed
+	c.broker.m.Unlock()
+
+	if consuming || closed {
+		return ErrConsumerOnly
+	}
+
+	msg := &message{
+		body: append([]byte(nil), body...),
+	}
+
+	c.broker.push(queueName, msg, c.broker.opts.deliveryDelay)
+
+	return nil
+}
+
+func (c *Channel) Consume(ctx context.Context, q string, h queue.Handler) error {
+	c.broker.m.Lock()
+
+	// проверяем, не занят ли канал другим консюмером
+	if c.consuming {
+		c.broker.m.Unlock()
+
+		return ErrConsumerExists
+	}
+
+	c.consuming = true
+	c.broker.queue(q)
+	c.wg.Add(1)
+	c.broker.m.Unlock()
+
+	go func() {
+		defer c.wg.Done()
+
+		logger := zlog.FromLogger(zlog.Ctx(ctx).With().Str("queue", q).Logger())
+
+		for {
+			msg, ok := c.broker.pop(q, c)
+			if !ok {
+				return
+			}
+
+			c.consumeDelivery(logger.WithContext(context.Background()), q, h, msg)
+		}
+	}()
+
+	zlog.Ctx(ctx).Info().Str("queue", q).Msg("consumer started")
+
+	return nil
+}
+
+func (c *Channel) consumeDelivery(ctx context.Context, q string, h queue.Handler, msg *message) {
+	delivery := &Delivery{
+		ch:    c,
+		queue: q,
+		msg:   msg,
+	}
+
+	c.broker.m.Lock()
+	c.unacked[delivery] = struct{}{}
+	c.broker.m.Unlock()
+
+	err := h(ctx, delivery)
+	if err != nil {
+		delivery.Reject(ctx)
+	}
+
+	c.broker.m.Lock()
+	c.broker.queue(q).handling--
+	c.broker.m.Unlock()
+}
+
+// settle завершает доставку, при requeue сообщение возвращается в начало очереди
+func (c *Channel) settle(d *Delivery, requeue bool) bool {
+	c.broker.m.Lock()
+
+	if d.settled {
+		c.broker.m.Unlock()
+
+		return false
+	}
+
+	d.settled = true
+	delete(c.unacked, d)
+	c.broker.queue(d.queue).stats.Unacked--
+	c.broker.m.Unlock()
+
+	if requeue {
+		c.broker.push(d.queue, &message{
+			body:        d.msg.body,
+			redelivered: true,
+		}, c.broker.opts.redeliveryDelay)
+	}
+
+	return true
+}
@@ -0,0 +1,31 @@
+package memqueue
+
+import (
+	"context"
+
+	zlog "fsm-framework/misk/logger"
+)
+
+type Delivery struct {
+	ch    *Channel
+	queue string
+	msg   *message
+	// settled доставка уже подтверждена или отклонена, защищено broker.m
+	settled bool
+}
+
+func (d *Delivery) Ack(ctx context.Context) {
+	if !d.ch.settle(d, false) {
+		zlog.Ctx(ctx).Error().Msg("memqueue ack error: delivery already settled")
+	}
+}
+
+func (d *Delivery) Reject(ctx context.Context) {
+	if !d.ch.settle(d, true) {
+		zlog.Ctx(ctx).Error().Msg("memqueue reject error: delivery already settled")
+	}
+}
+
+func (d *Delivery) GetBody() []byte {
+	return d.msg.body
+}
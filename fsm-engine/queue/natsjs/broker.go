@@ -0,0 +1,170 @@
+package natsjs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+const (
+	defaultStream       = "FSM"
+	defaultSubject      = "fsm"
+	defaultAckWait      = 5 * time.Minute
+	defaultPollInterval = 5 * time.Second
+)
+
+type Config struct {
+	// Conn соединение с nats, закрывается вызывающей стороной
+	Conn *nats.Conn
+	// Group имя сервиса, durable консюмеры очередей называются <Group>_<queue>
+	Group string
+	// Stream стрим JetStream (по умолчанию FSM), создается при отсутствии
+	Stream string
+	// Subject префикс subject очередей (по умолчанию fsm), очередь – <Subject>.<queue>
+	Subject string
+	// Storage хранилище стрима (по умолчанию file)
+	Storage nats.StorageType
+	// AckWait время, спустя которое неподтвержденное сообщение доставляется повторно (по умолчанию 5m)
+	AckWait time.Duration
+	// RetryDelay задержка повторной доставки отклоненного (Reject) сообщения (Nak с задержкой)
+	RetryDelay time.Duration
+	// MaxDeliver максимальное кол-во доставок сообщения (0 – без ограничения), сообщение,
+	// отклоненное на последней доставке, считается poison и перекладывается в DeadLetterQueue
+	MaxDeliver int
+	// DeadLetterQueue очередь для poison сообщений (пусто – сообщения удаляются с ошибкой в логе)
+	DeadLetterQueue string
+	// PollInterval время ожидания новых сообщений (по умолчанию 5s)
+	PollInterval time.Duration
+}
+
+// Broker брокер сообщений поверх NATS JetStream с durable pull консюмерами
+type Broker struct {
+	cfg Config
+	js  nats.JetStreamContext
+
+	m        sync.Mutex
+	channels []*Channel
+}
+
+func NewBroker(cfg Config) (queue.Broker, error) {
+	if cfg.Conn == nil || cfg.Group == "" {
+		return nil, errors.New("natsjs: conn and group are required")
+	}
+
+	if cfg.Stream == "" {
+		cfg.Stream = defaultStream
+	}
+
+	if cfg.Subject == "" {
+		cfg.Subject = defaultSubject
+	}
+
+	if cfg.AckWait == 0 {
+		cfg.AckWait = defaultAckWait
+	}
+
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+
+	js, err := cfg.Conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("natsjs jetstream context error: %w", err)
+	}
+
+	_, err = js.StreamInfo(cfg.Stream)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:      cfg.Stream,
+			Subjects:  []string{cfg.Subject + ".>"},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   cfg.Storage,
+		})
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("natsjs stream creation error: %w", err)
+	}
+
+	return &Broker{
+		cfg: cfg,
+		js:  js,
+	}, nil
+}
+
+func (b *Broker) Channel() (queue.Channel, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ch := &Channel{
+		broker:  b,
+		unacked: make(map[*Delivery]struct{}),
+		closed:  make(chan struct{}),
+	}
+	b.channels = append(b.channels, ch)
+
+	return ch, nil
+}
+
+func (b *Broker) Close(ctx context.Context) {
+	b.m.Lock()
+	channels := b.channels
+	b.channels = nil
+	b.m.Unlock()
+
+	for _, ch := range channels {
+		if err := ch.Close(); err != nil {
+			zlog.Ctx(ctx).Err(err).Msg("error while closing broker channel")
+		}
+	}
+}
+
+func (b *Broker) subject(queueName string) string {
+	return b.cfg.Subject + "." + queueName
+}
+
+// durable имя durable консюмера очереди (без символов, недопустимых в имени)
+func (b *Broker) durable(queueName string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_").Replace(b.cfg.Group + "_" + queueName)
+}
+
+// declare создает durable консюмер очереди, общий для всех каналов сервиса
+func (b *Broker) declare(queueName string) error {
+	durable := b.durable(queueName)
+
+	_, err := b.js.ConsumerInfo(b.cfg.Stream, durable)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, nats.ErrConsumerNotFound) {
+		return fmt.Errorf("natsjs consumer info error: %w", err)
+	}
+
+	maxDeliver := b.cfg.MaxDeliver
+	if maxDeliver == 0 {
+		maxDeliver = -1
+	}
+
+	_, err = b.js.AddConsumer(b.cfg.Stream, &nats.ConsumerConfig{
+		Durable:       durable,
+		DeliverPolicy: nats.DeliverAllPolicy,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       b.cfg.AckWait,
+		MaxDeliver:    maxDeliver,
+		FilterSubject: b.subject(queueName),
+	})
+	if err != nil {
+		return fmt.Errorf("natsjs consumer creation error: %w", err)
+	}
+
+	return nil
+}
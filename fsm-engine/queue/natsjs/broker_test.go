@@ -0,0 +1,111 @@
+package natsjs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"fsm-framework/fsm-engine/queue"
+	"fsm-framework/fsm-engine/queue/queuetest"
+)
+
+// runServer встроенный nats-server с JetStream
+func runServer(t *testing.T) *server.Server {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	go ns.Start()
+	t.Cleanup(ns.Shutdown)
+
+	require.True(t, ns.ReadyForConnections(10*time.Second), "nats-server is not ready")
+
+	return ns
+}
+
+func TestBroker(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) queue.Broker {
+		nc, err := nats.Connect(runServer(t).ClientURL())
+		require.NoError(t, err)
+		t.Cleanup(nc.Close)
+
+		b, err := NewBroker(Config{
+			Conn:         nc,
+			Group:        "queuetest",
+			Storage:      nats.MemoryStorage,
+			AckWait:      time.Second,
+			PollInterval: 50 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		return b
+	})
+}
+
+func TestBrokerDeadLetter(t *testing.T) {
+	nc, err := nats.Connect(runServer(t).ClientURL())
+	require.NoError(t, err)
+
+	defer nc.Close()
+
+	b, err := NewBroker(Config{
+		Conn:            nc,
+		Group:           "queuetest",
+		Storage:         nats.MemoryStorage,
+		MaxDeliver:      2,
+		DeadLetterQueue: "dead",
+		PollInterval:    50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	defer b.Close(context.Background())
+
+	pub, err := b.Channel()
+	require.NoError(t, err)
+
+	consumer, err := b.Channel()
+	require.NoError(t, err)
+
+	var deliveries atomic.Int32
+
+	err = consumer.Consume(context.Background(), "poison", func(ctx context.Context, d queue.Delivery) error {
+		deliveries.Inc()
+
+		return errors.New("handler error")
+	})
+	require.NoError(t, err)
+
+	dead := make(chan string, 1)
+
+	dlq, err := b.Channel()
+	require.NoError(t, err)
+
+	err = dlq.Consume(context.Background(), "dead", func(ctx context.Context, d queue.Delivery) error {
+		d.Ack(ctx)
+		dead <- string(d.GetBody())
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	pub.Publish(context.Background(), "poison", []byte("msg"))
+
+	select {
+	case body := <-dead:
+		require.Equal(t, "msg", body)
+	case <-time.After(queuetest.Timeout):
+		t.Fatal("poison message was not moved to dead letter queue")
+	}
+
+	require.Equal(t, int32(2), deliveries.Load())
+}
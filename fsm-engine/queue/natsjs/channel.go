@@ -0,0 +1,204 @@
+package natsjs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/opentracing/opentracing-go"
+	"go.uber.org/atomic"
+
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+var (
+	ErrConsumerExists = errors.New("consumer already exists")
+	ErrConsumerOnly   = errors.New("publish canceled, consumer-only channel")
+)
+
+type Channel struct {
+	broker *Broker
+	// consuming true – канал уже используется для консюминга (канал может быть использован только для чего-то одного)
+	consuming atomic.Bool
+	sub       *nats.Subscription
+	closeOnce sync.Once
+	closed    chan struct{}
+	// wg ожидание завершения обработчика текущего сообщения
+	wg sync.WaitGroup
+
+	m sync.Mutex
+	// unacked неподтвержденные доставки, при закрытии канала доставляются повторно
+	unacked map[*Delivery]struct{}
+}
+
+func (c *Channel) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	c.wg.Wait()
+
+	c.m.Lock()
+	unacked := c.unacked
+	c.unacked = make(map[*Delivery]struct{})
+	c.m.Unlock()
+
+	var err error
+
+	for d := range unacked {
+		if nakErr := d.msg.Nak(); nakErr != nil {
+			err = nakErr
+		}
+	}
+
+	// консюмер создан явно (declare), поэтому отписка его не удаляет
+	if c.sub != nil {
+		if unsubErr := c.sub.Unsubscribe(); unsubErr != nil && !errors.Is(unsubErr, nats.ErrConnectionClosed) {
+			err = unsubErr
+		}
+	}
+
+	return err
+}
+
+func (c *Channel) DeclareQueue(name string) error {
+	return c.broker.declare(name)
+}
+
+// Publish кладет сообщение в очередь, контекст трейсинга передается в заголовках сообщения
+func (c *Channel) Publish(ctx context.Context, queueName string, body []byte) {
+	err := c.PublishConfirm(ctx, queueName, body)
+	if err == nil {
+		return
+	}
+
+	// todo: retry
+	zlog.Ctx(ctx).Error().Err(err).Msg("natsjs publish error")
+}
+
+func (c *Channel) PublishConfirm(ctx context.Context, queueName string, body []byte) error {
+	if c.consuming.Load() {
+		return ErrConsumerOnly
+	}
+
+	msg := nats.NewMsg(c.broker.subject(queueName))
+	msg.Data = body
+
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		err := span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders,
+			opentracing.HTTPHeadersCarrier(http.Header(msg.Header)))
+		if err != nil {
+			zlog.Ctx(ctx).Warn().Err(err).Msg("natsjs trace context inject error")
+		}
+	}
+
+	_, err := c.broker.js.PublishMsg(msg, nats.Context(ctx))
+
+	return err
+}
+
+func (c *Channel) Consume(ctx context.Context, q string, h queue.Handler) error {
+	// проверяем, не занят ли канал другим консюмером
+	if !c.consuming.CAS(false, true) {
+		return ErrConsumerExists
+	}
+
+	err := c.broker.declare(q)
+	if err != nil {
+		c.consuming.Store(false)
+
+		return err
+	}
+
+	c.sub, err = c.broker.js.PullSubscribe(c.broker.subject(q), c.broker.durable(q),
+		nats.Bind(c.broker.cfg.Stream, c.broker.durable(q)), nats.ManualAck())
+	if err != nil {
+		c.consuming.Store(false)
+
+		return err
+	}
+
+	c.wg.Add(1)
+
+	go func() {
+		defer c.wg.Done()
+
+		logger := zlog.FromLogger(zlog.Ctx(ctx).With().Str("queue", q).Logger())
+		ctxWithLogger := logger.WithContext(context.Background())
+
+		for {
+			select {
+			case <-c.closed:
+				return
+			default:
+			}
+
+			msgs, err := c.sub.Fetch(1, nats.MaxWait(c.broker.cfg.PollInterval))
+			if err != nil && !errors.Is(err, nats.ErrTimeout) {
+				zlog.Ctx(ctxWithLogger).Error().Err(err).Msg("natsjs consume error")
+
+				select {
+				case <-c.closed:
+					return
+				case <-time.After(c.broker.cfg.PollInterval):
+				}
+
+				continue
+			}
+
+			for _, msg := range msgs {
+				c.consumeDelivery(ctxWithLogger, h, &Delivery{ch: c, msg: msg})
+			}
+		}
+	}()
+
+	zlog.Ctx(ctx).Info().Str("queue", q).Msg("consumer started")
+
+	return nil
+}
+
+func (c *Channel) consumeDelivery(ctx context.Context, h queue.Handler, d *Delivery) {
+	c.m.Lock()
+	c.unacked[d] = struct{}{}
+	c.m.Unlock()
+
+	// канал закрыт, пока ожидали сообщение: оно будет возвращено при закрытии
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+
+	// продолжаем трейс публикатора, если он передан в заголовках
+	spanCtx, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(http.Header(d.msg.Header)))
+	if err == nil {
+		span := opentracing.StartSpan("natsjs delivery", opentracing.FollowsFrom(spanCtx))
+		defer span.Finish()
+
+		ctx = opentracing.ContextWithSpan(ctx, span)
+	}
+
+	err = h(ctx, d)
+	if err != nil {
+		d.Reject(ctx)
+	}
+}
+
+// settle отмечает доставку завершенной, возвращает false, если она уже была подтверждена или отклонена
+func (c *Channel) settle(d *Delivery) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, ok := c.unacked[d]; !ok {
+		return false
+	}
+
+	delete(c.unacked, d)
+
+	return true
+}
@@ -0,0 +1,103 @@
+package natsjs
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+
+	zlog "fsm-framework/misk/logger"
+)
+
+type Delivery struct {
+	ch  *Channel
+	msg *nats.Msg
+}
+
+func (d *Delivery) Ack(ctx context.Context) {
+	if !d.ch.settle(d) {
+		zlog.Ctx(ctx).Error().Msg("natsjs ack error: delivery already settled")
+		return
+	}
+
+	err := d.msg.Ack()
+	if err == nil {
+		return
+	}
+
+	// todo: retry
+	zlog.Ctx(ctx).Error().Err(err).Msg("natsjs ack error")
+}
+
+// Reject повторная доставка спустя RetryDelay (Nak с задержкой),
+// сообщение, отклоненное на последней доставке (MaxDeliver), перекладывается в DeadLetterQueue
+func (d *Delivery) Reject(ctx context.Context) {
+	if !d.ch.settle(d) {
+		zlog.Ctx(ctx).Error().Msg("natsjs reject error: delivery already settled")
+		return
+	}
+
+	if d.isLastDelivery() {
+		d.deadLetter(ctx)
+
+		return
+	}
+
+	var err error
+
+	if delay := d.ch.broker.cfg.RetryDelay; delay > 0 {
+		err = d.msg.NakWithDelay(delay)
+	} else {
+		err = d.msg.Nak()
+	}
+
+	if err == nil {
+		return
+	}
+
+	// todo: retry
+	zlog.Ctx(ctx).Error().Err(err).Msg("natsjs reject error")
+}
+
+func (d *Delivery) GetBody() []byte {
+	return d.msg.Data
+}
+
+func (d *Delivery) isLastDelivery() bool {
+	maxDeliver := d.ch.broker.cfg.MaxDeliver
+	if maxDeliver <= 0 {
+		return false
+	}
+
+	meta, err := d.msg.Metadata()
+	if err != nil {
+		return false
+	}
+
+	return meta.NumDelivered >= uint64(maxDeliver)
+}
+
+// deadLetter перекладывает poison сообщение в DeadLetterQueue и завершает его доставку
+func (d *Delivery) deadLetter(ctx context.Context) {
+	logger := zlog.Ctx(ctx).With().Str("subject", d.msg.Subject).Logger()
+
+	if dlq := d.ch.broker.cfg.DeadLetterQueue; dlq != "" {
+		msg := nats.NewMsg(d.ch.broker.subject(dlq))
+		msg.Data = d.msg.Data
+		msg.Header = d.msg.Header
+
+		_, err := d.ch.broker.js.PublishMsg(msg, nats.Context(ctx))
+		if err != nil {
+			logger.Error().Err(err).Msg("natsjs dead letter publish error")
+
+			// сообщение будет доставлено повторно по истечении AckWait
+			return
+		}
+	}
+
+	err := d.msg.Term()
+	if err != nil {
+		logger.Error().Err(err).Msg("natsjs term error")
+	}
+
+	logger.Error().Msg("natsjs poison message: max deliver exceeded")
+}
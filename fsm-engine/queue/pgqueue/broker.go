@@ -0,0 +1,283 @@
+package pgqueue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+const (
+	defaultTable             = "fsm_queue_jobs"
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultPollInterval      = 5 * time.Second
+	listenerMinReconnect     = time.Second
+	listenerMaxReconnect     = time.Minute
+)
+
+var tableNameRe = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id           bigserial PRIMARY KEY,
+	queue        text        NOT NULL,
+	body         bytea       NOT NULL,
+	available_at timestamptz NOT NULL DEFAULT now(),
+	attempts     int         NOT NULL DEFAULT 0,
+	created_at   timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS %[1]s_queue_available_idx ON %[1]s (queue, available_at, id);
+`
+
+type Config struct {
+	// DB пул соединений, закрывается вызывающей стороной
+	DB *sql.DB
+	// DSN строка подключения для LISTEN (отдельное соединение)
+	DSN string
+	// Table таблица сообщений (по умолчанию fsm_queue_jobs), создается при отсутствии
+	Table string
+	// VisibilityTimeout время, спустя которое неподтвержденное сообщение снова доступно консюмерам (по умолчанию 5m)
+	VisibilityTimeout time.Duration
+	// RetryDelay задержка доступности отклоненного (Reject) сообщения
+	RetryDelay time.Duration
+	// PollInterval опрос таблицы на случай пропущенных уведомлений (по умолчанию 5s)
+	PollInterval time.Duration
+}
+
+// Broker брокер сообщений поверх таблицы PostgreSQL: сообщения забираются через FOR UPDATE SKIP LOCKED,
+// консюмеры пробуждаются через LISTEN/NOTIFY
+type Broker struct {
+	cfg      Config
+	db       *sql.DB
+	channel  string
+	listener *pq.Listener
+
+	m        sync.Mutex
+	channels []*Channel
+	// waiters консюмеры, ожидающие сообщений, по очередям
+	waiters map[string]map[chan struct{}]struct{}
+
+	done chan struct{}
+}
+
+func NewBroker(ctx context.Context, cfg Config) (*Broker, error) {
+	if cfg.Table == "" {
+		cfg.Table = defaultTable
+	}
+
+	if !tableNameRe.MatchString(cfg.Table) {
+		return nil, fmt.Errorf("invalid table name %q", cfg.Table)
+	}
+
+	if cfg.VisibilityTimeout == 0 {
+		cfg.VisibilityTimeout = defaultVisibilityTimeout
+	}
+
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+
+	_, err := cfg.DB.ExecContext(ctx, fmt.Sprintf(schemaSQL, cfg.Table))
+	if err != nil {
+		return nil, fmt.Errorf("pgqueue schema creation error: %w", err)
+	}
+
+	b := &Broker{
+		cfg:     cfg,
+		db:      cfg.DB,
+		channel: cfg.Table,
+		waiters: make(map[string]map[chan struct{}]struct{}),
+		done:    make(chan struct{}),
+	}
+
+	b.listener = pq.NewListener(cfg.DSN, listenerMinReconnect, listenerMaxReconnect,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				zlog.Ctx(ctx).Error().Err(err).Msg("pgqueue listener error")
+			}
+		})
+
+	err = b.listener.Listen(b.channel)
+	if err != nil {
+		_ = b.listener.Close()
+
+		return nil, fmt.Errorf("pgqueue listen error: %w", err)
+	}
+
+	go b.dispatch()
+
+	return b, nil
+}
+
+func (b *Broker) Channel() (queue.Channel, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ch := &Channel{
+		broker:  b,
+		unacked: make(map[*Delivery]struct{}),
+		closed:  make(chan struct{}),
+	}
+	b.channels = append(b.channels, ch)
+
+	return ch, nil
+}
+
+func (b *Broker) Close(ctx context.Context) {
+	b.m.Lock()
+	channels := b.channels
+	b.channels = nil
+	b.m.Unlock()
+
+	for _, ch := range channels {
+		if err := ch.Close(); err != nil {
+			zlog.Ctx(ctx).Err(err).Msg("error while closing broker channel")
+		}
+	}
+
+	close(b.done)
+
+	if err := b.listener.Close(); err != nil {
+		zlog.Ctx(ctx).Err(err).Msg("error while closing pgqueue listener")
+	}
+}
+
+// PublishTx кладет сообщение в очередь в рамках транзакции БД: сообщение станет доступно консюмерам
+// только после фиксации транзакции (outbox), уведомление также доставляется при фиксации
+func (b *Broker) PublishTx(ctx context.Context, tx *sql.Tx, queueName string, body []byte) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (queue, body) VALUES ($1, $2)`, b.cfg.Table),
+		queueName, body)
+	if err != nil {
+		return fmt.Errorf("pgqueue insert error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, b.channel, queueName)
+	if err != nil {
+		return fmt.Errorf("pgqueue notify error: %w", err)
+	}
+
+	return nil
+}
+
+// publish кладет сообщение в очередь вне транзакции
+func (b *Broker) publish(ctx context.Context, queueName string, body []byte) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("pgqueue begin error: %w", err)
+	}
+
+	err = b.PublishTx(ctx, tx, queueName, body)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// claim забирает доступное сообщение очереди, скрывая его от других консюмеров на VisibilityTimeout
+func (b *Broker) claim(ctx context.Context, queueName string) (*Delivery, error) {
+	row := b.db.QueryRowContext(ctx, fmt.Sprintf(`
+UPDATE %[1]s SET available_at = now() + $2 * interval '1 microsecond', attempts = attempts + 1
+WHERE id = (
+	SELECT id FROM %[1]s
+	WHERE queue = $1 AND available_at <= now()
+	ORDER BY available_at, id
+	FOR UPDATE SKIP LOCKED
+	LIMIT 1
+)
+RETURNING id, body, attempts`, b.cfg.Table), queueName, b.cfg.VisibilityTimeout.Microseconds())
+
+	d := &Delivery{
+		queue: queueName,
+	}
+
+	err := row.Scan(&d.id, &d.body, &d.attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("pgqueue claim error: %w", err)
+	}
+
+	return d, nil
+}
+
+// ack удаляет сообщение, если оно не было повторно выдано после истечения VisibilityTimeout
+func (b *Broker) ack(ctx context.Context, d *Delivery) error {
+	_, err := b.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND attempts = $2`, b.cfg.Table),
+		d.id, d.attempts)
+
+	return err
+}
+
+// reject делает сообщение снова доступным спустя delay
+func (b *Broker) reject(ctx context.Context, d *Delivery, delay time.Duration) error {
+	_, err := b.db.ExecContext(ctx, fmt.Sprintf(`
+UPDATE %s SET available_at = now() + $3 * interval '1 microsecond'
+WHERE id = $1 AND attempts = $2`, b.cfg.Table), d.id, d.attempts, delay.Microseconds())
+	if err != nil {
+		return err
+	}
+
+	if delay == 0 {
+		_, err = b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, b.channel, d.queue)
+	}
+
+	return err
+}
+
+// dispatch пробуждает консюмеров очереди из уведомления, при переподключении – всех консюмеров
+func (b *Broker) dispatch() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case n := <-b.listener.Notify:
+			b.m.Lock()
+
+			for q, waiters := range b.waiters {
+				if n != nil && n.Extra != q {
+					continue
+				}
+
+				for w := range waiters {
+					select {
+					case w <- struct{}{}:
+					default:
+					}
+				}
+			}
+
+			b.m.Unlock()
+		}
+	}
+}
+
+func (b *Broker) subscribe(queueName string, w chan struct{}) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.waiters[queueName] == nil {
+		b.waiters[queueName] = make(map[chan struct{}]struct{})
+	}
+
+	b.waiters[queueName][w] = struct{}{}
+}
+
+func (b *Broker) unsubscribe(queueName string, w chan struct{}) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	delete(b.waiters[queueName], w)
+}
@@ -0,0 +1,189 @@
+package pgqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+var (
+	ErrConsumerExists = errors.New("consumer already exists")
+	ErrConsumerOnly   = errors.New("publish canceled, consumer-only channel")
+)
+
+const (
+	// retryMinBackoff, retryMaxBackoff задержки повторов неудавшихся операций с таблицей сообщений
+	retryMinBackoff = 100 * time.Millisecond
+	retryMaxBackoff = 5 * time.Second
+)
+
+type Channel struct {
+	broker *Broker
+	// consuming true – канал уже используется для консюминга (канал может быть использован только для чего-то одного)
+	consuming atomic.Bool
+	closeOnce sync.Once
+	closed    chan struct{}
+	// wg ожидание завершения обработчика текущего сообщения
+	wg sync.WaitGroup
+
+	m sync.Mutex
+	// unacked неподтвержденные доставки, возвращаются в очередь при закрытии канала
+	unacked map[*Delivery]struct{}
+}
+
+func (c *Channel) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	c.wg.Wait()
+
+	c.m.Lock()
+	unacked := c.unacked
+	c.unacked = make(map[*Delivery]struct{})
+	c.m.Unlock()
+
+	var err error
+
+	for d := range unacked {
+		if rejectErr := c.broker.reject(context.Background(), d, 0); rejectErr != nil {
+			err = rejectErr
+		}
+	}
+
+	return err
+}
+
+// DeclareQueue очереди не требуют объявления, все сообщения хранятся в одной таблице
+func (c *Channel) DeclareQueue(name string) error {
+	return nil
+}
+
+// Publish публикует сообщение, повторяя попытки, пока оно не будет записано, не отменен ctx или не закрыт канал
+func (c *Channel) Publish(ctx context.Context, queueName string, body []byte) {
+	err := c.retry(ctx, "publish", func() error {
+		return c.PublishConfirm(ctx, queueName, body)
+	})
+	if err != nil {
+		zlog.Ctx(ctx).Error().Err(err).Msg("pgqueue publish error")
+	}
+}
+
+func (c *Channel) PublishConfirm(ctx context.Context, queueName string, body []byte) error {
+	if c.consuming.Load() {
+		return ErrConsumerOnly
+	}
+
+	return c.broker.publish(ctx, queueName, body)
+}
+
+func (c *Channel) Consume(ctx context.Context, q string, h queue.Handler) error {
+	// проверяем, не занят ли канал другим консюмером
+	if !c.consuming.CAS(false, true) {
+		return ErrConsumerExists
+	}
+
+	wakeup := make(chan struct{}, 1)
+	c.broker.subscribe(q, wakeup)
+
+	c.wg.Add(1)
+
+	go func() {
+		defer c.wg.Done()
+		defer c.broker.unsubscribe(q, wakeup)
+
+		logger := zlog.FromLogger(zlog.Ctx(ctx).With().Str("queue", q).Logger())
+		ctxWithLogger := logger.WithContext(context.Background())
+
+		for {
+			select {
+			case <-c.closed:
+				return
+			default:
+			}
+
+			d, err := c.broker.claim(ctxWithLogger, q)
+			if err != nil {
+				zlog.Ctx(ctxWithLogger).Error().Err(err).Msg("pgqueue consume error")
+			}
+
+			if d != nil {
+				c.consumeDelivery(ctxWithLogger, h, d)
+
+				continue
+			}
+
+			select {
+			case <-c.closed:
+				return
+			case <-wakeup:
+			case <-time.After(c.broker.cfg.PollInterval):
+			}
+		}
+	}()
+
+	zlog.Ctx(ctx).Info().Str("queue", q).Msg("consumer started")
+
+	return nil
+}
+
+func (c *Channel) consumeDelivery(ctx context.Context, h queue.Handler, d *Delivery) {
+	d.ch = c
+
+	c.m.Lock()
+	c.unacked[d] = struct{}{}
+	c.m.Unlock()
+
+	err := h(ctx, d)
+	if err != nil {
+		d.Reject(ctx)
+	}
+}
+
+// retry повторяет операцию op с экспоненциальной задержкой, пока она не завершится успешно,
+// не будет отменен ctx или закрыт канал. Возвращает последнюю ошибку операции
+func (c *Channel) retry(ctx context.Context, op string, f func() error) error {
+	backoff := retryMinBackoff
+
+	for {
+		err := f()
+		if err == nil || errors.Is(err, ErrConsumerOnly) {
+			return err
+		}
+
+		zlog.Ctx(ctx).Warn().Err(err).Dur("backoff", backoff).Msgf("pgqueue %s error, retrying", op)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-c.closed:
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > retryMaxBackoff {
+			backoff = retryMaxBackoff
+		}
+	}
+}
+
+// settle отмечает доставку завершенной, возвращает false, если она уже была подтверждена или отклонена
+func (c *Channel) settle(d *Delivery) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, ok := c.unacked[d]; !ok {
+		return false
+	}
+
+	delete(c.unacked, d)
+
+	return true
+}
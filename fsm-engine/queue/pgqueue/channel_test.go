@@ -0,0 +1,88 @@
+package pgqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errUnavailable = errors.New("database is unavailable")
+
+func TestChannelRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		// failures количество неудачных попыток до успешной (< 0 – всегда)
+		failures int
+		err      error
+		calls    int
+		// cancel отмена ctx после первой попытки
+		cancel bool
+		// close закрытие канала после первой попытки
+		close bool
+	}{
+		{name: "success", calls: 1},
+		{name: "retried until success", failures: 2, calls: 3},
+		{name: "consumer-only channel not retried", failures: -1, err: ErrConsumerOnly, calls: 1},
+		{name: "ctx canceled", failures: -1, err: errUnavailable, calls: 1, cancel: true},
+		{name: "channel closed", failures: -1, err: errUnavailable, calls: 1, close: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ch := &Channel{closed: make(chan struct{})}
+			calls := 0
+
+			err := ch.retry(ctx, "test", func() error {
+				calls++
+
+				if tt.cancel {
+					cancel()
+				}
+
+				if tt.close {
+					close(ch.closed)
+				}
+
+				if tt.failures < 0 || calls <= tt.failures {
+					if tt.err != nil {
+						return tt.err
+					}
+
+					return errUnavailable
+				}
+
+				return nil
+			})
+
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestChannelRetryBackoff(t *testing.T) {
+	ch := &Channel{closed: make(chan struct{})}
+
+	var attempts []time.Time
+
+	err := ch.retry(context.Background(), "test", func() error {
+		attempts = append(attempts, time.Now())
+		if len(attempts) < 3 {
+			return errUnavailable
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 3)
+	// задержка удваивается после каждой неудачи
+	assert.GreaterOrEqual(t, int64(attempts[1].Sub(attempts[0])), int64(retryMinBackoff))
+	assert.GreaterOrEqual(t, int64(attempts[2].Sub(attempts[1])), int64(2*retryMinBackoff))
+}
@@ -0,0 +1,50 @@
+package pgqueue
+
+import (
+	"context"
+
+	zlog "fsm-framework/misk/logger"
+)
+
+type Delivery struct {
+	ch    *Channel
+	queue string
+	id    int64
+	body  []byte
+	// attempts номер выдачи сообщения, защищает от подтверждения повторно выданного сообщения
+	attempts int
+}
+
+func (d *Delivery) Ack(ctx context.Context) {
+	if !d.ch.settle(d) {
+		zlog.Ctx(ctx).Error().Msg("pgqueue ack error: delivery already settled")
+		return
+	}
+
+	// при неудаче сообщение станет доступно повторно по истечении VisibilityTimeout
+	err := d.ch.retry(ctx, "ack", func() error {
+		return d.ch.broker.ack(ctx, d)
+	})
+	if err != nil {
+		zlog.Ctx(ctx).Error().Err(err).Msg("pgqueue ack error")
+	}
+}
+
+func (d *Delivery) Reject(ctx context.Context) {
+	if !d.ch.settle(d) {
+		zlog.Ctx(ctx).Error().Msg("pgqueue reject error: delivery already settled")
+		return
+	}
+
+	// при неудаче сообщение станет доступно повторно по истечении VisibilityTimeout
+	err := d.ch.retry(ctx, "reject", func() error {
+		return d.ch.broker.reject(ctx, d, d.ch.broker.cfg.RetryDelay)
+	})
+	if err != nil {
+		zlog.Ctx(ctx).Error().Err(err).Msg("pgqueue reject error")
+	}
+}
+
+func (d *Delivery) GetBody() []byte {
+	return d.body
+}
@@ -28,6 +28,14 @@ type Channel interface {
 	Consume(ctx context.Context, queue string, handler Handler) error
 }
 
+// ConfirmChannel канал, сообщающий, что брокер не принял сообщение. Используется там, где потеря сообщения
+// недопустима и публикацию следует повторить (записи о переходах транзакций из outbox)
+type ConfirmChannel interface {
+	Channel
+	// PublishConfirm кладет сообщение в очередь, возвращает ошибку, если сообщение не было принято брокером
+	PublishConfirm(ctx context.Context, queue string, body []byte) error
+}
+
 type Broker interface {
 	// Close закрывает все соединения с очередью
 	Close(ctx context.Context)
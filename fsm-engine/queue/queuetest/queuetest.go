@@ -0,0 +1,250 @@
+// Package queuetest общий набор проверок реализаций queue.Broker на семантику, на которую рассчитывает fsm-движок
+package queuetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/queue"
+)
+
+// Timeout ожидание доставки сообщения
+var Timeout = 10 * time.Second
+
+// NewBroker создает брокер для очередной проверки, брокер закрывается по завершении проверки
+type NewBroker func(t *testing.T) queue.Broker
+
+// Run запускает все проверки реализации брокера
+func Run(t *testing.T, newBroker NewBroker) {
+	t.Run("PublishConsume", func(t *testing.T) { testPublishConsume(t, newBroker) })
+	t.Run("RejectRedelivers", func(t *testing.T) { testRejectRedelivers(t, newBroker) })
+	t.Run("HandlerErrorRejects", func(t *testing.T) { testHandlerErrorRejects(t, newBroker) })
+	t.Run("MultipleQueues", func(t *testing.T) { testMultipleQueues(t, newBroker) })
+	t.Run("CompetingConsumers", func(t *testing.T) { testCompetingConsumers(t, newBroker) })
+	t.Run("CloseRequeuesUnacked", func(t *testing.T) { testCloseRequeuesUnacked(t, newBroker) })
+}
+
+// QueueName уникальное имя очереди, чтобы проверки не пересекались на внешнем брокере
+func QueueName() string {
+	return fmt.Sprintf("queuetest_%s", uuid.New().String()[:8])
+}
+
+type env struct {
+	t      *testing.T
+	ctx    context.Context
+	broker queue.Broker
+	pub    queue.Channel
+}
+
+func newEnv(t *testing.T, newBroker NewBroker) *env {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	broker := newBroker(t)
+
+	pub, err := broker.Channel()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		broker.Close(ctx)
+		cancel()
+	})
+
+	return &env{
+		t:      t,
+		ctx:    ctx,
+		broker: broker,
+		pub:    pub,
+	}
+}
+
+// consume запускает консюмер на новом канале, полученные сообщения передаются в канал
+func (e *env) consume(q string, h func(d queue.Delivery) error) queue.Channel {
+	ch, err := e.broker.Channel()
+	require.NoError(e.t, err)
+
+	err = ch.Consume(e.ctx, q, func(_ context.Context, d queue.Delivery) error {
+		return h(d)
+	})
+	require.NoError(e.t, err)
+
+	return ch
+}
+
+func (e *env) publish(q string, bodies ...string) {
+	for _, body := range bodies {
+		e.pub.Publish(e.ctx, q, []byte(body))
+	}
+}
+
+func receive(t *testing.T, ch <-chan string) string {
+	select {
+	case body := <-ch:
+		return body
+	case <-time.After(Timeout):
+		t.Fatal("message was not delivered in time")
+	}
+
+	return ""
+}
+
+func testPublishConsume(t *testing.T, newBroker NewBroker) {
+	e := newEnv(t, newBroker)
+	q := QueueName()
+	received := make(chan string, 3)
+
+	e.consume(q, func(d queue.Delivery) error {
+		d.Ack(e.ctx)
+		received <- string(d.GetBody())
+
+		return nil
+	})
+	e.publish(q, "1", "2", "3")
+
+	require.Equal(t, "1", receive(t, received))
+	require.Equal(t, "2", receive(t, received))
+	require.Equal(t, "3", receive(t, received))
+}
+
+func testRejectRedelivers(t *testing.T, newBroker NewBroker) {
+	e := newEnv(t, newBroker)
+	q := QueueName()
+	received := make(chan string, 2)
+
+	var n int
+
+	e.consume(q, func(d queue.Delivery) error {
+		n++
+		if n == 1 {
+			d.Reject(e.ctx)
+		} else {
+			d.Ack(e.ctx)
+		}
+
+		received <- string(d.GetBody())
+
+		return nil
+	})
+	e.publish(q, "msg")
+
+	require.Equal(t, "msg", receive(t, received))
+	require.Equal(t, "msg", receive(t, received))
+}
+
+func testHandlerErrorRejects(t *testing.T, newBroker NewBroker) {
+	e := newEnv(t, newBroker)
+	q := QueueName()
+	received := make(chan string, 2)
+
+	var n int
+
+	e.consume(q, func(d queue.Delivery) error {
+		n++
+		received <- string(d.GetBody())
+
+		if n == 1 {
+			return errors.New("handler error")
+		}
+
+		d.Ack(e.ctx)
+
+		return nil
+	})
+	e.publish(q, "msg")
+
+	require.Equal(t, "msg", receive(t, received))
+	require.Equal(t, "msg", receive(t, received))
+}
+
+func testMultipleQueues(t *testing.T, newBroker NewBroker) {
+	e := newEnv(t, newBroker)
+	q1, q2 := QueueName(), QueueName()
+	received1, received2 := make(chan string, 2), make(chan string, 2)
+
+	e.consume(q1, func(d queue.Delivery) error {
+		d.Ack(e.ctx)
+		received1 <- string(d.GetBody())
+
+		return nil
+	})
+	e.consume(q2, func(d queue.Delivery) error {
+		d.Ack(e.ctx)
+		received2 <- string(d.GetBody())
+
+		return nil
+	})
+	e.publish(q1, "q1")
+	e.publish(q2, "q2")
+
+	require.Equal(t, "q1", receive(t, received1))
+	require.Equal(t, "q2", receive(t, received2))
+}
+
+func testCompetingConsumers(t *testing.T, newBroker NewBroker) {
+	const n = 20
+
+	e := newEnv(t, newBroker)
+	q := QueueName()
+	received := make(chan string, n*2)
+
+	for i := 0; i < 2; i++ {
+		e.consume(q, func(d queue.Delivery) error {
+			d.Ack(e.ctx)
+			received <- string(d.GetBody())
+
+			return nil
+		})
+	}
+
+	for i := 0; i < n; i++ {
+		e.publish(q, fmt.Sprint(i))
+	}
+
+	seen := make(map[string]bool, n)
+
+	for i := 0; i < n; i++ {
+		body := receive(t, received)
+		require.False(t, seen[body], "message %s delivered twice", body)
+		seen[body] = true
+	}
+
+	select {
+	case body := <-received:
+		t.Fatalf("unexpected message %s", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func testCloseRequeuesUnacked(t *testing.T, newBroker NewBroker) {
+	e := newEnv(t, newBroker)
+	q := QueueName()
+	first, second := make(chan string, 1), make(chan string, 1)
+
+	var once sync.Once
+
+	ch := e.consume(q, func(d queue.Delivery) error {
+		// сообщение не подтверждается
+		once.Do(func() { first <- string(d.GetBody()) })
+
+		return nil
+	})
+	e.publish(q, "msg")
+
+	require.Equal(t, "msg", receive(t, first))
+	require.NoError(t, ch.Close())
+
+	e.consume(q, func(d queue.Delivery) error {
+		d.Ack(e.ctx)
+		second <- string(d.GetBody())
+
+		return nil
+	})
+
+	require.Equal(t, "msg", receive(t, second))
+}
@@ -14,7 +14,10 @@ import (
 	zlog "fsm-framework/misk/logger"
 )
 
-var ErrConsumerExists = errors.New("consumer already exists")
+var (
+	ErrConsumerExists = errors.New("consumer already exists")
+	ErrConsumerOnly   = errors.New("publish canceled, consumer-only channel")
+)
 
 type Channel struct {
 	// appName имя сервиса, чтобы прокинуть в metadata (пример: morpheus)
@@ -44,11 +47,20 @@ func (c *Channel) DeclareQueue(name string) error {
 }
 
 func (c *Channel) Publish(ctx context.Context, queue string, body []byte) {
-	if c.consuming.Load() {
-		zlog.Ctx(ctx).Error().Msg("publish canceled, consumer-only channel")
+	err := c.PublishConfirm(ctx, queue, body)
+	if err == nil {
 		return
 	}
 
+	// todo: retry
+	zlog.Ctx(ctx).Error().Err(err).Msg("rabbitmq publish error")
+}
+
+func (c *Channel) PublishConfirm(ctx context.Context, queue string, body []byte) error {
+	if c.consuming.Load() {
+		return ErrConsumerOnly
+	}
+
 	msg := amqp.Publishing{
 		ContentType:  "application/json",
 		DeliveryMode: amqp.Persistent,
@@ -57,13 +69,7 @@ func (c *Channel) Publish(ctx context.Context, queue string, body []byte) {
 		Body:         body,
 	}
 
-	err := c.ch.Publish("", string(queue), false, false, msg)
-	if err == nil {
-		return
-	}
-
-	// todo: retry
-	zlog.Ctx(ctx).Error().Err(err).Msg("rabbitmq publish error")
+	return c.ch.Publish("", string(queue), false, false, msg)
 }
 
 func (c *Channel) consumeDelivery(ctx context.Context, h queue.Handler, d amqp.Delivery) {
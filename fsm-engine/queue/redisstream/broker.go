@@ -0,0 +1,193 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+const (
+	defaultPrefix            = "fsm_stream:"
+	defaultVisibilityTimeout = 5 * time.Minute
+	defaultPollInterval      = 5 * time.Second
+	// bodyField поле записи стрима с телом сообщения
+	bodyField = "body"
+)
+
+type Config struct {
+	// Client клиент redis (например, redislock.NewClient), закрывается вызывающей стороной
+	Client redis.UniversalClient
+	// Group группа консюмеров, обычно имя сервиса
+	Group string
+	// Prefix префикс ключей стримов (по умолчанию fsm_stream:), очередь состояния – отдельный стрим
+	Prefix string
+	// VisibilityTimeout время простоя неподтвержденного сообщения,
+	// после которого его забирает другой консюмер (XAUTOCLAIM), по умолчанию 5m
+	VisibilityTimeout time.Duration
+	// RetryDelay задержка повторной доставки отклоненного (Reject) сообщения
+	RetryDelay time.Duration
+	// PollInterval время ожидания новых сообщений и период проверки зависших (по умолчанию 5s)
+	PollInterval time.Duration
+}
+
+// Broker брокер сообщений поверх Redis Streams с группами консюмеров
+type Broker struct {
+	cfg    Config
+	client redis.UniversalClient
+
+	m        sync.Mutex
+	channels []*Channel
+}
+
+func NewBroker(cfg Config) (queue.Broker, error) {
+	if cfg.Client == nil || cfg.Group == "" {
+		return nil, errors.New("redisstream: client and group are required")
+	}
+
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultPrefix
+	}
+
+	if cfg.VisibilityTimeout == 0 {
+		cfg.VisibilityTimeout = defaultVisibilityTimeout
+	}
+
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+
+	return &Broker{
+		cfg:    cfg,
+		client: cfg.Client,
+	}, nil
+}
+
+func (b *Broker) Channel() (queue.Channel, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ch := &Channel{
+		broker:   b,
+		consumer: b.cfg.Group + "-" + uuid.New().String(),
+		unacked:  make(map[*Delivery]struct{}),
+		closed:   make(chan struct{}),
+	}
+	b.channels = append(b.channels, ch)
+
+	return ch, nil
+}
+
+func (b *Broker) Close(ctx context.Context) {
+	b.m.Lock()
+	channels := b.channels
+	b.channels = nil
+	b.m.Unlock()
+
+	for _, ch := range channels {
+		if err := ch.Close(); err != nil {
+			zlog.Ctx(ctx).Err(err).Msg("error while closing broker channel")
+		}
+	}
+}
+
+func (b *Broker) stream(queueName string) string {
+	return b.cfg.Prefix + queueName
+}
+
+// declare создает стрим и группу консюмеров, группа читает стрим с начала
+func (b *Broker) declare(ctx context.Context, queueName string) error {
+	err := b.client.XGroupCreateMkStream(ctx, b.stream(queueName), b.cfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("redisstream group creation error: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Broker) publish(ctx context.Context, queueName string, body []byte) error {
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream(queueName),
+		Values: map[string]interface{}{bodyField: body},
+	}).Err()
+}
+
+// claim забирает зависшее (или отклоненное) сообщение, а при его отсутствии ожидает новое
+func (b *Broker) claim(ctx context.Context, queueName string, consumer string) (*Delivery, error) {
+	msgs, _, err := b.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   b.stream(queueName),
+		Group:    b.cfg.Group,
+		MinIdle:  b.cfg.VisibilityTimeout,
+		Start:    "0-0",
+		Count:    1,
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redisstream autoclaim error: %w", err)
+	}
+
+	if len(msgs) == 0 {
+		streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    b.cfg.Group,
+			Consumer: consumer,
+			Streams:  []string{b.stream(queueName), ">"},
+			Count:    1,
+			Block:    b.cfg.PollInterval,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("redisstream read error: %w", err)
+		}
+
+		for _, stream := range streams {
+			msgs = append(msgs, stream.Messages...)
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	body, _ := msgs[0].Values[bodyField].(string)
+
+	return &Delivery{
+		queue: queueName,
+		id:    msgs[0].ID,
+		body:  []byte(body),
+	}, nil
+}
+
+func (b *Broker) ack(ctx context.Context, d *Delivery) error {
+	stream := b.stream(d.queue)
+
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, stream, b.cfg.Group, d.id)
+		pipe.XDel(ctx, stream, d.id)
+
+		return nil
+	})
+
+	return err
+}
+
+// reject выставляет простой сообщения так, чтобы его забрали (XAUTOCLAIM) спустя RetryDelay
+func (b *Broker) reject(ctx context.Context, d *Delivery, consumer string) error {
+	idle := b.cfg.VisibilityTimeout - b.cfg.RetryDelay
+	if idle < 0 {
+		idle = 0
+	}
+
+	return b.client.Do(ctx, "XCLAIM", b.stream(d.queue), b.cfg.Group, consumer, 0, d.id,
+		"IDLE", idle.Milliseconds(), "JUSTID").Err()
+}
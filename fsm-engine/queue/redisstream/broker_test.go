@@ -0,0 +1,42 @@
+package redisstream
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/queue"
+	"fsm-framework/fsm-engine/queue/queuetest"
+)
+
+// testAddr локальный redis из FSM_TEST_REDIS_ADDR, либо miniredis
+func testAddr(t *testing.T) string {
+	if addr := os.Getenv("FSM_TEST_REDIS_ADDR"); addr != "" {
+		return addr
+	}
+
+	return miniredis.RunT(t).Addr()
+}
+
+func TestBroker(t *testing.T) {
+	queuetest.Run(t, func(t *testing.T) queue.Broker {
+		client := redis.NewClient(&redis.Options{
+			Addr: testAddr(t),
+		})
+		t.Cleanup(func() { _ = client.Close() })
+
+		b, err := NewBroker(Config{
+			Client:            client,
+			Group:             "queuetest",
+			VisibilityTimeout: time.Second,
+			PollInterval:      50 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		return b
+	})
+}
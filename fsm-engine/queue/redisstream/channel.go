@@ -0,0 +1,165 @@
+package redisstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+var (
+	ErrConsumerExists = errors.New("consumer already exists")
+	ErrConsumerOnly   = errors.New("publish canceled, consumer-only channel")
+)
+
+type Channel struct {
+	broker *Broker
+	// consumer имя консюмера в группе, уникально для канала
+	consumer string
+	// consuming true – канал уже используется для консюминга (канал может быть использован только для чего-то одного)
+	consuming atomic.Bool
+	closeOnce sync.Once
+	closed    chan struct{}
+	// wg ожидание завершения обработчика текущего сообщения
+	wg sync.WaitGroup
+
+	m sync.Mutex
+	// unacked неподтвержденные доставки, при закрытии канала становятся доступны другим консюмерам
+	unacked map[*Delivery]struct{}
+}
+
+func (c *Channel) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+
+	c.wg.Wait()
+
+	c.m.Lock()
+	unacked := c.unacked
+	c.unacked = make(map[*Delivery]struct{})
+	c.m.Unlock()
+
+	var err error
+
+	for d := range unacked {
+		if rejectErr := c.broker.reject(context.Background(), d, c.consumer); rejectErr != nil {
+			err = rejectErr
+		}
+	}
+
+	return err
+}
+
+func (c *Channel) DeclareQueue(name string) error {
+	return c.broker.declare(context.Background(), name)
+}
+
+func (c *Channel) Publish(ctx context.Context, queueName string, body []byte) {
+	err := c.PublishConfirm(ctx, queueName, body)
+	if err == nil {
+		return
+	}
+
+	// todo: retry
+	zlog.Ctx(ctx).Error().Err(err).Msg("redisstream publish error")
+}
+
+func (c *Channel) PublishConfirm(ctx context.Context, queueName string, body []byte) error {
+	if c.consuming.Load() {
+		return ErrConsumerOnly
+	}
+
+	return c.broker.publish(ctx, queueName, body)
+}
+
+func (c *Channel) Consume(ctx context.Context, q string, h queue.Handler) error {
+	// проверяем, не занят ли канал другим консюмером
+	if !c.consuming.CAS(false, true) {
+		return ErrConsumerExists
+	}
+
+	err := c.broker.declare(ctx, q)
+	if err != nil {
+		c.consuming.Store(false)
+
+		return err
+	}
+
+	c.wg.Add(1)
+
+	go func() {
+		defer c.wg.Done()
+
+		logger := zlog.FromLogger(zlog.Ctx(ctx).With().Str("queue", q).Logger())
+		ctxWithLogger := logger.WithContext(context.Background())
+
+		for {
+			select {
+			case <-c.closed:
+				return
+			default:
+			}
+
+			d, err := c.broker.claim(ctxWithLogger, q, c.consumer)
+			if err != nil {
+				zlog.Ctx(ctxWithLogger).Error().Err(err).Msg("redisstream consume error")
+
+				select {
+				case <-c.closed:
+					return
+				case <-time.After(c.broker.cfg.PollInterval):
+				}
+
+				continue
+			}
+
+			if d != nil {
+				c.consumeDelivery(ctxWithLogger, h, d)
+			}
+		}
+	}()
+
+	zlog.Ctx(ctx).Info().Str("queue", q).Msg("consumer started")
+
+	return nil
+}
+
+func (c *Channel) consumeDelivery(ctx context.Context, h queue.Handler, d *Delivery) {
+	d.ch = c
+
+	c.m.Lock()
+	c.unacked[d] = struct{}{}
+	c.m.Unlock()
+
+	// канал закрыт, пока ожидали сообщение: оно будет возвращено при закрытии
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+
+	err := h(ctx, d)
+	if err != nil {
+		d.Reject(ctx)
+	}
+}
+
+// settle отмечает доставку завершенной, возвращает false, если она уже была подтверждена или отклонена
+func (c *Channel) settle(d *Delivery) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if _, ok := c.unacked[d]; !ok {
+		return false
+	}
+
+	delete(c.unacked, d)
+
+	return true
+}
@@ -0,0 +1,48 @@
+package redisstream
+
+import (
+	"context"
+
+	zlog "fsm-framework/misk/logger"
+)
+
+type Delivery struct {
+	ch    *Channel
+	queue string
+	id    string
+	body  []byte
+}
+
+func (d *Delivery) Ack(ctx context.Context) {
+	if !d.ch.settle(d) {
+		zlog.Ctx(ctx).Error().Msg("redisstream ack error: delivery already settled")
+		return
+	}
+
+	err := d.ch.broker.ack(ctx, d)
+	if err == nil {
+		return
+	}
+
+	// todo: retry
+	zlog.Ctx(ctx).Error().Err(err).Msg("redisstream ack error")
+}
+
+func (d *Delivery) Reject(ctx context.Context) {
+	if !d.ch.settle(d) {
+		zlog.Ctx(ctx).Error().Msg("redisstream reject error: delivery already settled")
+		return
+	}
+
+	err := d.ch.broker.reject(ctx, d, d.ch.consumer)
+	if err == nil {
+		return
+	}
+
+	// todo: retry
+	zlog.Ctx(ctx).Error().Err(err).Msg("redisstream reject error")
+}
+
+func (d *Delivery) GetBody() []byte {
+	return d.body
+}
@@ -0,0 +1,59 @@
+package maprate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/ratelimit"
+)
+
+// Limiter ограничивает частоту в пределах одного процесса (GCRA),
+// подходит для тестов и сервисов, запущенных в одном экземпляре
+type Limiter struct {
+	m     sync.Mutex
+	tat   map[string]time.Time
+	clock clock.Clock
+}
+
+// NewLimiter clk – часы движка (fsmengine.Config.Clock), nil – системное время
+func NewLimiter(clk clock.Clock) (ratelimit.Limiter, error) {
+	if clk == nil {
+		clk = clock.Real{}
+	}
+
+	return &Limiter{
+		tat:   map[string]time.Time{},
+		clock: clk,
+	}, nil
+}
+
+func (l *Limiter) Close() error {
+	return nil
+}
+
+func (l *Limiter) Reserve(ctx context.Context, key string, limit *model.RateLimit) (time.Duration, error) {
+	emission, burst := ratelimit.Emission(limit)
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	now := l.clock.Now()
+
+	tat := l.tat[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	tat = tat.Add(emission)
+	l.tat[key] = tat
+
+	wait := tat.Add(-time.Duration(burst) * emission).Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+
+	return wait, nil
+}
@@ -0,0 +1,80 @@
+package maprate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/ratelimit"
+)
+
+func reserve(t *testing.T, l ratelimit.Limiter, key string, limit *model.RateLimit) time.Duration {
+	t.Helper()
+
+	wait, err := l.Reserve(context.Background(), key, limit)
+	require.NoError(t, err)
+
+	return wait
+}
+
+func TestReserve(t *testing.T) {
+	limit := &model.RateLimit{Rate: 10, Burst: 3}
+
+	tests := []struct {
+		name string
+		// advance сдвиг часов перед каждым резервированием
+		advance time.Duration
+		want    []time.Duration
+	}{
+		{
+			name: "burst",
+			want: []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:    "steady rate",
+			advance: 100 * time.Millisecond,
+			want:    []time.Duration{0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "faster than rate",
+			advance: 50 * time.Millisecond,
+			want:    []time.Duration{0, 0, 0, 0, 0, 50 * time.Millisecond, 100 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+
+			l, err := NewLimiter(clk)
+			require.NoError(t, err)
+
+			got := make([]time.Duration, 0, len(tt.want))
+
+			for range tt.want {
+				clk.Advance(tt.advance)
+				got = append(got, reserve(t, l, "STATE", limit))
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReserveKeys(t *testing.T) {
+	limit := &model.RateLimit{Rate: 1}
+
+	l, err := NewLimiter(clock.NewFake(time.Now()))
+	require.NoError(t, err)
+
+	// ключи ограничиваются независимо
+	assert.Zero(t, reserve(t, l, "FIRST", limit))
+	assert.Zero(t, reserve(t, l, "SECOND", limit))
+	assert.Equal(t, time.Second, reserve(t, l, "FIRST", limit))
+	assert.Equal(t, time.Second, reserve(t, l, "SECOND", limit))
+}
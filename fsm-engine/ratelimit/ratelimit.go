@@ -0,0 +1,25 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+type Limiter interface {
+	// Reserve резервирует обработку одного события по ключу,
+	// возвращает время, которое необходимо подождать перед обработкой
+	Reserve(ctx context.Context, key string, limit *model.RateLimit) (time.Duration, error)
+	Close() error
+}
+
+// Emission интервал между событиями и размер burst (не меньше 1) для ограничения
+func Emission(limit *model.RateLimit) (time.Duration, int) {
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	return time.Duration(float64(time.Second) / limit.Rate), burst
+}
@@ -0,0 +1,72 @@
+package redisrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/ratelimit"
+)
+
+const keyPrefix = "fsm_rate_limit:"
+
+// reserveScript GCRA с резервированием: каждый вызов занимает следующий слот,
+// возвращает кол-во микросекунд до него. Время берется у redis, чтобы не зависеть от часов реплик
+var reserveScript = redis.NewScript(`
+redis.replicate_commands()
+
+local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+
+tat = tat + emission
+redis.call('SET', KEYS[1], tat, 'PX', math.ceil((tat - now) / 1000) + 1)
+
+local wait = tat - burst * emission - now
+if wait < 0 then
+	wait = 0
+end
+
+return wait
+`)
+
+// Limiter распределенное ограничение частоты для всех реплик сервиса
+type Limiter struct {
+	redisClient redis.UniversalClient
+	appName     string
+}
+
+// NewLimiter создает ограничитель поверх клиента redis (например, redislock.NewClient),
+// клиент limiter не закрывает
+func NewLimiter(client redis.UniversalClient, appName string) ratelimit.Limiter {
+	return &Limiter{
+		redisClient: client,
+		appName:     appName,
+	}
+}
+
+func (l *Limiter) Close() error {
+	return nil
+}
+
+func (l *Limiter) Reserve(ctx context.Context, key string, limit *model.RateLimit) (time.Duration, error) {
+	emission, burst := ratelimit.Emission(limit)
+
+	wait, err := reserveScript.Run(ctx, l.redisClient, []string{keyPrefix + l.appName + ":" + key},
+		emission.Microseconds(), burst).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("rate limit reserve err: %w", err)
+	}
+
+	return time.Duration(wait) * time.Microsecond, nil
+}
@@ -0,0 +1,102 @@
+package redisrate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/ratelimit"
+)
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Now())
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return mr, client
+}
+
+func reserve(t *testing.T, l ratelimit.Limiter, key string, limit *model.RateLimit) time.Duration {
+	t.Helper()
+
+	wait, err := l.Reserve(context.Background(), key, limit)
+	require.NoError(t, err)
+
+	return wait
+}
+
+func TestReserve(t *testing.T) {
+	limit := &model.RateLimit{Rate: 10, Burst: 3}
+
+	tests := []struct {
+		name string
+		// advance сдвиг времени redis перед каждым резервированием
+		advance time.Duration
+		want    []time.Duration
+	}{
+		{
+			name: "burst",
+			want: []time.Duration{0, 0, 0, 100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:    "steady rate",
+			advance: 100 * time.Millisecond,
+			want:    []time.Duration{0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "faster than rate",
+			advance: 50 * time.Millisecond,
+			want:    []time.Duration{0, 0, 0, 0, 0, 50 * time.Millisecond, 100 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, client := newRedis(t)
+			l := NewLimiter(client, "app")
+			now := time.Now()
+
+			got := make([]time.Duration, 0, len(tt.want))
+
+			for range tt.want {
+				now = now.Add(tt.advance)
+				mr.SetTime(now)
+
+				got = append(got, reserve(t, l, "STATE", limit))
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReserveShared(t *testing.T) {
+	limit := &model.RateLimit{Rate: 1, Burst: 2}
+
+	_, client := newRedis(t)
+
+	// реплики одного сервиса делят ограничение
+	first := NewLimiter(client, "app")
+	second := NewLimiter(client, "app")
+
+	assert.Zero(t, reserve(t, first, "STATE", limit))
+	assert.Zero(t, reserve(t, second, "STATE", limit))
+	assert.Equal(t, time.Second, reserve(t, first, "STATE", limit))
+	assert.Equal(t, 2*time.Second, reserve(t, second, "STATE", limit))
+
+	// другие сервисы и состояния ограничиваются независимо
+	assert.Zero(t, reserve(t, NewLimiter(client, "other"), "STATE", limit))
+	assert.Zero(t, reserve(t, first, "OTHER_STATE", limit))
+}
@@ -0,0 +1,59 @@
+package memrepo
+
+import (
+	"github.com/google/uuid"
+
+	http_cbm "fsm-framework/fsm-engine/callback-manager/http-cbm"
+	"fsm-framework/fsm-engine/model"
+)
+
+// Transactions копии всех транзакций в порядке создания
+func (r *Repository) Transactions() []model.Tx {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	txs := make([]model.Tx, 0, len(r.order))
+	for _, id := range r.order {
+		txs = append(txs, r.clone(r.txs[id]))
+	}
+
+	return txs
+}
+
+// Path последовательность состояний, в которых побывала транзакция (начиная с начального)
+func (r *Repository) Path(txID uuid.UUID) []string {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	return append([]string(nil), r.paths[txID]...)
+}
+
+// EventsOf события транзакции в порядке записи
+func (r *Repository) EventsOf(txID uuid.UUID) []*model.Event {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	events := make([]*model.Event, 0, len(r.events[txID]))
+	for _, ev := range r.events[txID] {
+		events = append(events, copyEvent(ev))
+	}
+
+	return events
+}
+
+// CallbackEvents события обратных вызовов транзакции
+func (r *Repository) CallbackEvents(txID uuid.UUID) []*http_cbm.CallbackEvent {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	var events []*http_cbm.CallbackEvent
+
+	for _, ev := range r.callbacks {
+		if ev.TxID == txID {
+			cp := *ev
+			events = append(events, &cp)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,353 @@
+package memrepo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	http_cbm "fsm-framework/fsm-engine/callback-manager/http-cbm"
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/model"
+)
+
+var (
+	_ model.Repository            = &Repository{}
+	_ model.IdempotencyRepository = &Repository{}
+	_ model.TransitionRepository  = &Repository{}
+	_ http_cbm.Repository         = &Repository{}
+)
+
+var (
+	// ErrTxNotFound транзакция не найдена
+	ErrTxNotFound = errors.New("memrepo: transaction not found")
+	// ErrTxExists транзакция с таким ID уже создана
+	ErrTxExists = errors.New("memrepo: transaction already exists")
+	// ErrStateMismatch текущее состояние транзакции отличается от ожидаемого (транзакцию уже перевели)
+	ErrStateMismatch = errors.New("memrepo: transaction state mismatch")
+	// ErrCallbackEventNotFound событие обратного вызова не найдено
+	ErrCallbackEventNotFound = errors.New("memrepo: callback event not found")
+)
+
+// CloneFunc глубокая копия транзакции: репозиторий хранит и отдает копии,
+// чтобы изменения транзакции вызывающей стороной не попадали в хранилище без UpdateTransaction
+type CloneFunc func(tx model.Tx) model.Tx
+
+type idempotencyKey struct {
+	txID      uuid.UUID
+	expiresAt time.Time
+}
+
+// Repository потокобезопасная реализация model.Repository, model.IdempotencyRepository, model.TransitionRepository
+// и http_cbm.Repository в памяти
+// для тестов и локальной разработки, UpdateTransaction выполняет compare-and-swap по текущему состоянию
+type Repository struct {
+	clone CloneFunc
+	// clock источник времени истечения ключей идемпотентности
+	clock clock.Clock
+
+	m         sync.RWMutex
+	txs       map[uuid.UUID]model.Tx
+	order     []uuid.UUID
+	paths     map[uuid.UUID][]string
+	events    map[uuid.UUID][]*model.Event
+	keys      map[string]idempotencyKey
+	callbacks map[uuid.UUID]*http_cbm.CallbackEvent
+	// outbox неопубликованные записи о переходах в порядке сохранения
+	outbox []*model.TransitionRecord
+}
+
+type Option func(r *Repository)
+
+// WithClock источник времени истечения ключей идемпотентности (по умолчанию системное время)
+func WithClock(clk clock.Clock) Option {
+	return func(r *Repository) {
+		r.clock = clk
+	}
+}
+
+func New(clone CloneFunc, opts ...Option) *Repository {
+	r := &Repository{
+		clone:     clone,
+		clock:     clock.Real{},
+		txs:       make(map[uuid.UUID]model.Tx),
+		paths:     make(map[uuid.UUID][]string),
+		events:    make(map[uuid.UUID][]*model.Event),
+		keys:      make(map[string]idempotencyKey),
+		callbacks: make(map[uuid.UUID]*http_cbm.CallbackEvent),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func (r *Repository) Transaction(ctx context.Context, txID uuid.UUID) (model.Tx, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	tx, ok := r.txs[txID]
+	if !ok {
+		return nil, ErrTxNotFound
+	}
+
+	return r.clone(tx), nil
+}
+
+// UpdateTransaction обновляет транзакцию, только если её текущее состояние совпадает с currState
+func (r *Repository) UpdateTransaction(ctx context.Context, tx model.Tx, currState string) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.update(tx, currState)
+}
+
+// update вызывается под r.m
+func (r *Repository) update(tx model.Tx, currState string) error {
+	stored, ok := r.txs[tx.ID()]
+	if !ok {
+		return ErrTxNotFound
+	}
+
+	if stateName(stored) != currState {
+		return ErrStateMismatch
+	}
+
+	r.txs[tx.ID()] = r.clone(tx)
+
+	if name := stateName(tx); name != currState {
+		r.paths[tx.ID()] = append(r.paths[tx.ID()], name)
+	}
+
+	return nil
+}
+
+// UpdateTransactionWithRecord обновляет транзакцию как UpdateTransaction, запись сохраняется, только если
+// транзакция обновлена
+func (r *Repository) UpdateTransactionWithRecord(ctx context.Context, tx model.Tx, currState string,
+	record *model.TransitionRecord) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	err := r.update(tx, currState)
+	if err != nil {
+		return err
+	}
+
+	r.outbox = append(r.outbox, copyRecord(record))
+
+	return nil
+}
+
+func (r *Repository) PendingTransitionRecords(ctx context.Context, limit int) ([]*model.TransitionRecord, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if limit > len(r.outbox) {
+		limit = len(r.outbox)
+	}
+
+	records := make([]*model.TransitionRecord, 0, limit)
+	for _, record := range r.outbox[:limit] {
+		records = append(records, copyRecord(record))
+	}
+
+	return records, nil
+}
+
+func (r *Repository) DeleteTransitionRecords(ctx context.Context, ids []uuid.UUID) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	deleted := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		deleted[id] = struct{}{}
+	}
+
+	outbox := r.outbox[:0]
+
+	for _, record := range r.outbox {
+		if _, ok := deleted[record.ID]; !ok {
+			outbox = append(outbox, record)
+		}
+	}
+
+	r.outbox = outbox
+
+	return nil
+}
+
+func (r *Repository) TransactionsByState(ctx context.Context, state string) ([]model.Tx, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	var txs []model.Tx
+
+	for _, id := range r.order {
+		if tx := r.txs[id]; stateName(tx) == state {
+			txs = append(txs, r.clone(tx))
+		}
+	}
+
+	return txs, nil
+}
+
+func (r *Repository) CreateTransaction(ctx context.Context, tx model.Tx) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.create(tx)
+}
+
+// create вызывается под r.m
+func (r *Repository) create(tx model.Tx) error {
+	if _, ok := r.txs[tx.ID()]; ok {
+		return ErrTxExists
+	}
+
+	r.txs[tx.ID()] = r.clone(tx)
+	r.order = append(r.order, tx.ID())
+	r.paths[tx.ID()] = []string{stateName(tx)}
+
+	return nil
+}
+
+func (r *Repository) CreateTransactionIdempotent(ctx context.Context, tx model.Tx, key string,
+	retention time.Duration) (model.Tx, bool, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if k, ok := r.keys[key]; ok && r.clock.Now().Before(k.expiresAt) {
+		stored, ok := r.txs[k.txID]
+		if !ok {
+			return nil, false, ErrTxNotFound
+		}
+
+		return r.clone(stored), false, nil
+	}
+
+	err := r.create(tx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.keys[key] = idempotencyKey{
+		txID:      tx.ID(),
+		expiresAt: r.clock.Now().Add(retention),
+	}
+
+	return tx, true, nil
+}
+
+// UpdateEvent сохраняет копию события, Event.Tx не сохраняется
+func (r *Repository) UpdateEvent(ctx context.Context, event *model.Event) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	ev := copyEvent(event)
+	txID := event.Tx.ID()
+
+	for i, stored := range r.events[txID] {
+		if stored.ID == ev.ID {
+			r.events[txID][i] = ev
+
+			return nil
+		}
+	}
+
+	r.events[txID] = append(r.events[txID], ev)
+
+	return nil
+}
+
+func (r *Repository) Events(ctx context.Context, txID uuid.UUID) ([]*model.Event, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	events := make([]*model.Event, 0, len(r.events[txID]))
+	for _, ev := range r.events[txID] {
+		events = append(events, copyEvent(ev))
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Created.Before(events[j].Created)
+	})
+
+	return events, nil
+}
+
+func (r *Repository) CountActiveTransactions(ctx context.Context, modelName string, version string) (int, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	var n int
+
+	for _, tx := range r.txs {
+		if tx.State() == nil || tx.State().Model().Name() != modelName || tx.ModelVersion() != version {
+			continue
+		}
+
+		if tx.Status() == model.TxStatusPending || tx.Status() == model.TxStatusProgress {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (r *Repository) CallbackEvent(ctx context.Context, cbEventID uuid.UUID) (*http_cbm.CallbackEvent, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	ev, ok := r.callbacks[cbEventID]
+	if !ok {
+		return nil, ErrCallbackEventNotFound
+	}
+
+	cp := *ev
+
+	return &cp, nil
+}
+
+func (r *Repository) UpdateCallbackEvent(ctx context.Context, cbEvent *http_cbm.CallbackEvent) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	cp := *cbEvent
+	r.callbacks[cbEvent.ID] = &cp
+
+	return nil
+}
+
+func stateName(tx model.Tx) string {
+	if tx.State() == nil {
+		return ""
+	}
+
+	return tx.State().Name()
+}
+
+func copyEvent(event *model.Event) *model.Event {
+	ev := *event
+	ev.Tx = nil
+	ev.Data = nil
+	ev.Compensations = append([]string(nil), event.Compensations...)
+
+	return &ev
+}
+
+func copyRecord(record *model.TransitionRecord) *model.TransitionRecord {
+	cp := *record
+	cp.TraceContext = make(map[string]string, len(record.TraceContext))
+
+	for k, v := range record.TraceContext {
+		cp.TraceContext[k] = v
+	}
+
+	return &cp
+}
@@ -0,0 +1,182 @@
+package memrepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/clock"
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/repository/memrepo"
+	test_model "fsm-framework/fsm-engine/test-model"
+)
+
+// newTx транзакция в состоянии state со статусом status
+func newTx(state model.State, status model.TxStatus) *fsmtest.Tx {
+	tx := fsmtest.NewTx()
+	tx.SetState(state)
+	tx.SetStatus(status)
+
+	return tx
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New(fsmtest.CloneTx)
+
+	tx := newTx(test_model.FooState, model.TxStatusPending)
+	require.NoError(t, repo.CreateTransaction(ctx, tx))
+	require.ErrorIs(t, repo.CreateTransaction(ctx, tx), memrepo.ErrTxExists)
+
+	// изменения без UpdateTransaction не видны
+	tx.SetStatus(model.TxStatusProgress)
+	stored, err := repo.Transaction(ctx, tx.ID())
+	require.NoError(t, err)
+	require.Equal(t, model.TxStatusPending, stored.Status())
+
+	tx.SetState(test_model.BarState)
+	require.NoError(t, repo.UpdateTransaction(ctx, tx, test_model.FooState.Name()))
+
+	// повторный переход из того же состояния конфликтует
+	require.ErrorIs(t, repo.UpdateTransaction(ctx, tx, test_model.FooState.Name()), memrepo.ErrStateMismatch)
+
+	stored, err = repo.Transaction(ctx, tx.ID())
+	require.NoError(t, err)
+	require.Equal(t, test_model.BarState, stored.State())
+	require.Equal(t, []string{test_model.FooState.Name(), test_model.BarState.Name()}, repo.Path(tx.ID()))
+
+	byState, err := repo.TransactionsByState(ctx, test_model.BarState.Name())
+	require.NoError(t, err)
+	require.Len(t, byState, 1)
+
+	_, err = repo.Transaction(ctx, uuid.New())
+	require.ErrorIs(t, err, memrepo.ErrTxNotFound)
+}
+
+func TestCreateTransactionIdempotent(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New(fsmtest.CloneTx)
+	key := uuid.New().String()
+
+	first := newTx(test_model.FooState, model.TxStatusPending)
+	stored, created, err := repo.CreateTransactionIdempotent(ctx, first, key, time.Hour)
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, first.ID(), stored.ID())
+
+	second := newTx(test_model.FooState, model.TxStatusPending)
+	stored, created, err = repo.CreateTransactionIdempotent(ctx, second, key, time.Hour)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, first.ID(), stored.ID())
+	require.Len(t, repo.Transactions(), 1)
+}
+
+func TestIdempotencyKeyExpiry(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	repo := memrepo.New(fsmtest.CloneTx, memrepo.WithClock(clk))
+	key := uuid.New().String()
+
+	first := newTx(test_model.FooState, model.TxStatusPending)
+	_, created, err := repo.CreateTransactionIdempotent(ctx, first, key, time.Hour)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	clk.Advance(time.Hour - time.Second)
+
+	second := newTx(test_model.FooState, model.TxStatusPending)
+	stored, created, err := repo.CreateTransactionIdempotent(ctx, second, key, time.Hour)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, first.ID(), stored.ID())
+
+	// истекший ключ используется для новой транзакции
+	clk.Advance(time.Second)
+
+	stored, created, err = repo.CreateTransactionIdempotent(ctx, second, key, time.Hour)
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, second.ID(), stored.ID())
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New(fsmtest.CloneTx)
+
+	tx := newTx(test_model.FooState, model.TxStatusPending)
+	event := &model.Event{ID: uuid.New(), Tx: tx, Status: model.EventStatusProgress, Created: time.Now()}
+	require.NoError(t, repo.UpdateEvent(ctx, event))
+
+	event.Status = model.EventStatusDone
+	require.NoError(t, repo.UpdateEvent(ctx, event))
+
+	events, err := repo.Events(ctx, tx.ID())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, model.EventStatusDone, events[0].Status)
+	require.Nil(t, events[0].Tx)
+}
+
+func TestCountActiveTransactions(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New(fsmtest.CloneTx)
+
+	statuses := []model.TxStatus{model.TxStatusPending, model.TxStatusProgress, model.TxStatusError, model.TxStatusDone}
+	for _, status := range statuses {
+		tx := newTx(test_model.FooState, status)
+		tx.SetModelVersion("1")
+		require.NoError(t, repo.CreateTransaction(ctx, tx))
+	}
+
+	// транзакция другой версии
+	other := newTx(test_model.FooState, model.TxStatusPending)
+	other.SetModelVersion("2")
+	require.NoError(t, repo.CreateTransaction(ctx, other))
+
+	// транзакции в статусах error и done версию не удерживают
+	n, err := repo.CountActiveTransactions(ctx, test_model.Model.Name(), "1")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestTransitionRecords(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New(fsmtest.CloneTx)
+
+	tx := newTx(test_model.FooState, model.TxStatusPending)
+	require.NoError(t, repo.CreateTransaction(ctx, tx))
+
+	first := &model.TransitionRecord{ID: uuid.New(), TxID: tx.ID(), FromState: test_model.FooState.Name()}
+	tx.SetState(test_model.BarState)
+	require.NoError(t, repo.UpdateTransactionWithRecord(ctx, tx, test_model.FooState.Name(), first))
+
+	// при конфликте обновления запись не сохраняется
+	lost := &model.TransitionRecord{ID: uuid.New(), TxID: tx.ID(), FromState: test_model.FooState.Name()}
+	require.ErrorIs(t, repo.UpdateTransactionWithRecord(ctx, tx, test_model.FooState.Name(), lost), memrepo.ErrStateMismatch)
+
+	second := &model.TransitionRecord{ID: uuid.New(), TxID: tx.ID(), FromState: test_model.BarState.Name(), Final: true}
+	tx.SetStatus(model.TxStatusDone)
+	require.NoError(t, repo.UpdateTransactionWithRecord(ctx, tx, test_model.BarState.Name(), second))
+
+	records, err := repo.PendingTransitionRecords(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, first.ID, records[0].ID)
+	require.Equal(t, second.ID, records[1].ID)
+
+	records, err = repo.PendingTransitionRecords(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	require.NoError(t, repo.DeleteTransitionRecords(ctx, []uuid.UUID{first.ID}))
+
+	records, err = repo.PendingTransitionRecords(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, second.ID, records[0].ID)
+}
@@ -0,0 +1,50 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	http_cbm "fsm-framework/fsm-engine/callback-manager/http-cbm"
+)
+
+func (r *Repository) CallbackEvent(ctx context.Context, cbEventID uuid.UUID) (*http_cbm.CallbackEvent, error) {
+	ev := &http_cbm.CallbackEvent{}
+
+	err := r.db.QueryRowContext(ctx, `
+SELECT id, tx_id, request_url, request_body, request_timestamp, response_code, response_body, retry_n,
+    sent_successfully
+FROM fsm_callback_event WHERE id = $1`, cbEventID).Scan(&ev.ID, &ev.TxID, &ev.RequestURL, &ev.RequestBody,
+		&ev.RequestTimestamp, &ev.ResponseCode, &ev.ResponseBody, &ev.RetryN, &ev.SentSuccessfully)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCallbackEventNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("pgrepo callback event: %w", err)
+	}
+
+	return ev, nil
+}
+
+func (r *Repository) UpdateCallbackEvent(ctx context.Context, cbEvent *http_cbm.CallbackEvent) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO fsm_callback_event (id, tx_id, request_url, request_body, request_timestamp, response_code,
+    response_body, retry_n, sent_successfully)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (id) DO UPDATE SET
+    request_timestamp = excluded.request_timestamp,
+    response_code = excluded.response_code,
+    response_body = excluded.response_body,
+    sent_successfully = excluded.sent_successfully`,
+		cbEvent.ID, cbEvent.TxID, cbEvent.RequestURL, cbEvent.RequestBody, cbEvent.RequestTimestamp,
+		cbEvent.ResponseCode, cbEvent.ResponseBody, cbEvent.RetryN, cbEvent.SentSuccessfully)
+	if err != nil {
+		return fmt.Errorf("pgrepo update callback event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package pgrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+// CreateTransactionIdempotent записывает транзакцию вместе с ключом идемпотентности в одной транзакции БД,
+// при конкурентной записи того же ключа вторая ожидает фиксации первой и возвращает её транзакцию
+func (r *Repository) CreateTransactionIdempotent(ctx context.Context, tx model.Tx, key string,
+	retention time.Duration) (model.Tx, bool, error) {
+	dbTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	defer func() {
+		_ = dbTx.Rollback()
+	}()
+
+	// истекший ключ освобождается для повторного использования
+	_, err = dbTx.ExecContext(ctx, `DELETE FROM fsm_tx_idempotency_key WHERE key = $1 AND expires_at < now()`, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("pgrepo expired idempotency key deletion: %w", err)
+	}
+
+	res, err := dbTx.ExecContext(ctx, `
+INSERT INTO fsm_tx_idempotency_key (key, tx_id, expires_at)
+VALUES ($1, $2, now() + $3 * interval '1 microsecond')
+ON CONFLICT (key) DO NOTHING`, key, tx.ID(), retention.Microseconds())
+	if err != nil {
+		return nil, false, fmt.Errorf("pgrepo idempotency key insertion: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if n == 0 {
+		var storedID uuid.UUID
+
+		err = dbTx.QueryRowContext(ctx, `SELECT tx_id FROM fsm_tx_idempotency_key WHERE key = $1`, key).
+			Scan(&storedID)
+		if err != nil {
+			return nil, false, fmt.Errorf("pgrepo idempotency key lookup: %w", err)
+		}
+
+		stored, err := r.transaction(ctx, dbTx, storedID)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return stored, false, nil
+	}
+
+	err = r.createTransaction(ctx, dbTx, tx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = dbTx.Commit()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return tx, true, nil
+}
@@ -0,0 +1,27 @@
+package pgrepo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+// TxMapper связывает структуру транзакции сервиса с таблицей fsm_tx:
+// базовые поля читаются и пишутся через model.Tx, собственные – через дополнительные колонки
+type TxMapper interface {
+	// NewTx пустая транзакция для чтения из БД
+	NewTx() model.Tx
+	// Columns дополнительные колонки fsm_tx (добавляются миграцией сервиса)
+	Columns() []string
+	// Values значения дополнительных колонок транзакции в порядке Columns
+	Values(tx model.Tx) ([]interface{}, error)
+	// Dest указатели на поля транзакции для чтения: ID и дополнительные колонки в порядке Columns
+	Dest(tx model.Tx) (id *uuid.UUID, columns []interface{})
+}
+
+// StateResolver разрешение состояния транзакции с учетом версии модели, обычно fsm-движок (Engine.ResolveVersion)
+type StateResolver interface {
+	ResolveVersion(ctx context.Context, state string, version string) (model.State, model.Model)
+}
@@ -0,0 +1,91 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrate применяет миграции таблиц транзакций, событий и обратных вызовов,
+// примененные миграции учитываются в fsm_schema_migrations.
+// Дополнительные колонки транзакций (TxMapper) добавляются миграциями сервиса
+func Migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS fsm_schema_migrations (
+    version text PRIMARY KEY,
+    applied timestamptz NOT NULL DEFAULT now()
+)`)
+	if err != nil {
+		return fmt.Errorf("schema migrations table creation error: %w", err)
+	}
+
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		err = applyMigration(ctx, db, name)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, name string) error {
+	version := strings.TrimSuffix(name, ".sql")
+
+	body, err := migrations.ReadFile("migrations/" + name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// блокировка исключает одновременное применение миграций несколькими репликами
+	_, err = tx.ExecContext(ctx, `LOCK TABLE fsm_schema_migrations IN EXCLUSIVE MODE`)
+	if err != nil {
+		return err
+	}
+
+	var applied bool
+
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM fsm_schema_migrations WHERE version = $1)`,
+		version).Scan(&applied)
+	if err != nil || applied {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, string(body))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO fsm_schema_migrations (version) VALUES ($1)`, version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
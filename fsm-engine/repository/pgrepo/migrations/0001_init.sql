@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS fsm_tx (
+    tx_id         uuid PRIMARY KEY,
+    model         text        NOT NULL,
+    model_version text        NOT NULL DEFAULT '',
+    state         text        NOT NULL,
+    status        text        NOT NULL,
+    trace_id      text        NOT NULL DEFAULT '',
+    span_id       text        NOT NULL DEFAULT '',
+    callback_url  text        NOT NULL DEFAULT '',
+    context_data  bytea,
+    created       timestamptz NOT NULL DEFAULT now(),
+    updated       timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS fsm_tx_state_idx ON fsm_tx (state);
+CREATE INDEX IF NOT EXISTS fsm_tx_model_version_status_idx ON fsm_tx (model, model_version, status);
+
+CREATE TABLE IF NOT EXISTS fsm_tx_idempotency_key (
+    key        text PRIMARY KEY,
+    tx_id      uuid        NOT NULL,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS fsm_event (
+    event_id          uuid PRIMARY KEY,
+    tx_id             uuid        NOT NULL,
+    event_type        text        NOT NULL,
+    start_state       text        NOT NULL,
+    final_state       text        NOT NULL DEFAULT '',
+    status            text        NOT NULL,
+    retry_n           int         NOT NULL DEFAULT 0,
+    span_id           text        NOT NULL DEFAULT '',
+    model_version     text        NOT NULL DEFAULT '',
+    compensated_state text        NOT NULL DEFAULT '',
+    compensations     text[]      NOT NULL DEFAULT '{}',
+    created           timestamptz NOT NULL,
+    updated           timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS fsm_event_tx_id_created_idx ON fsm_event (tx_id, created);
+
+CREATE TABLE IF NOT EXISTS fsm_callback_event (
+    id                uuid PRIMARY KEY,
+    tx_id             uuid        NOT NULL,
+    request_url       text        NOT NULL,
+    request_body      bytea,
+    request_timestamp timestamptz NOT NULL,
+    response_code     int         NOT NULL DEFAULT 0,
+    response_body     bytea,
+    retry_n           int         NOT NULL DEFAULT 0,
+    sent_successfully bool        NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS fsm_callback_event_tx_id_idx ON fsm_callback_event (tx_id);
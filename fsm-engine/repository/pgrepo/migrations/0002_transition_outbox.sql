@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS fsm_transition_outbox (
+    id      uuid PRIMARY KEY,
+    seq     bigserial   NOT NULL,
+    record  jsonb       NOT NULL,
+    created timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS fsm_transition_outbox_seq_idx ON fsm_transition_outbox (seq);
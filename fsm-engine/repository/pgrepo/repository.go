@@ -0,0 +1,324 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	http_cbm "fsm-framework/fsm-engine/callback-manager/http-cbm"
+	"fsm-framework/fsm-engine/model"
+)
+
+var (
+	_ model.Repository            = &Repository{}
+	_ model.IdempotencyRepository = &Repository{}
+	_ model.TransitionRepository  = &Repository{}
+	_ http_cbm.Repository         = &Repository{}
+)
+
+var (
+	// ErrTxNotFound транзакция не найдена
+	ErrTxNotFound = errors.New("pgrepo: transaction not found")
+	// ErrStateMismatch текущее состояние транзакции в БД отличается от ожидаемого (транзакцию уже перевели)
+	ErrStateMismatch = errors.New("pgrepo: transaction state mismatch")
+	// ErrCallbackEventNotFound событие обратного вызова не найдено
+	ErrCallbackEventNotFound = errors.New("pgrepo: callback event not found")
+)
+
+// txColumns базовые колонки fsm_tx в порядке чтения и записи
+var txColumns = []string{
+	"tx_id", "model", "model_version", "state", "status", "trace_id", "span_id", "callback_url", "context_data",
+}
+
+// querier общие методы sql.DB и sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Repository реализация model.Repository, model.IdempotencyRepository, model.TransitionRepository
+// и http_cbm.Repository поверх PostgreSQL,
+// схема создается Migrate
+type Repository struct {
+	db     *sql.DB
+	mapper TxMapper
+
+	m        sync.RWMutex
+	resolver StateResolver
+}
+
+// New создает репозиторий. Резолвер состояний (fsm-движок) задается после его создания через SetResolver
+func New(db *sql.DB, mapper TxMapper) *Repository {
+	return &Repository{
+		db:     db,
+		mapper: mapper,
+	}
+}
+
+// SetResolver задает резолвер состояний транзакций
+func (r *Repository) SetResolver(resolver StateResolver) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.resolver = resolver
+}
+
+func (r *Repository) Transaction(ctx context.Context, txID uuid.UUID) (model.Tx, error) {
+	return r.transaction(ctx, r.db, txID)
+}
+
+func (r *Repository) transaction(ctx context.Context, q querier, txID uuid.UUID) (model.Tx, error) {
+	row := q.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s FROM fsm_tx WHERE tx_id = $1`, r.selectColumns()), txID)
+
+	tx, err := r.scanTx(ctx, row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTxNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.State() == nil {
+		return nil, fmt.Errorf("pgrepo: state of tx %s is not resolved", txID)
+	}
+
+	return tx, nil
+}
+
+// UpdateTransaction обновляет транзакцию, только если её текущее состояние в БД совпадает с currState (CAS)
+func (r *Repository) UpdateTransaction(ctx context.Context, tx model.Tx, currState string) error {
+	return r.updateTransaction(ctx, r.db, tx, currState)
+}
+
+func (r *Repository) updateTransaction(ctx context.Context, q querier, tx model.Tx, currState string) error {
+	values, err := r.txValues(tx)
+	if err != nil {
+		return err
+	}
+
+	columns := r.columns()
+	sets := make([]string, 0, len(columns))
+
+	for i, column := range columns[1:] {
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, i+2))
+	}
+
+	query := fmt.Sprintf(`UPDATE fsm_tx SET %s, updated = now() WHERE tx_id = $1 AND state = $%d`,
+		strings.Join(sets, ", "), len(columns)+1)
+
+	res, err := q.ExecContext(ctx, query, append(values, currState)...)
+	if err != nil {
+		return fmt.Errorf("pgrepo update tx: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	var exists bool
+
+	err = q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM fsm_tx WHERE tx_id = $1)`, tx.ID()).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrTxNotFound
+	}
+
+	return ErrStateMismatch
+}
+
+func (r *Repository) TransactionsByState(ctx context.Context, state string) ([]model.Tx, error) {
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM fsm_tx WHERE state = $1 ORDER BY created`,
+		r.selectColumns()), state)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var txs []model.Tx
+
+	for rows.Next() {
+		tx, err := r.scanTx(ctx, rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+
+		txs = append(txs, tx)
+	}
+
+	return txs, rows.Err()
+}
+
+func (r *Repository) CreateTransaction(ctx context.Context, tx model.Tx) error {
+	return r.createTransaction(ctx, r.db, tx)
+}
+
+func (r *Repository) createTransaction(ctx context.Context, q querier, tx model.Tx) error {
+	values, err := r.txValues(tx)
+	if err != nil {
+		return err
+	}
+
+	columns := r.columns()
+	placeholders := make([]string, len(columns))
+
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	_, err = q.ExecContext(ctx, fmt.Sprintf(`INSERT INTO fsm_tx (%s) VALUES (%s)`,
+		strings.Join(columns, ", "), strings.Join(placeholders, ", ")), values...)
+	if err != nil {
+		return fmt.Errorf("pgrepo create tx: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) UpdateEvent(ctx context.Context, event *model.Event) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO fsm_event (event_id, tx_id, event_type, start_state, final_state, status, retry_n, span_id,
+    model_version, compensated_state, compensations, created, updated)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, now())
+ON CONFLICT (event_id) DO UPDATE SET
+    final_state = excluded.final_state,
+    status = excluded.status,
+    span_id = excluded.span_id,
+    updated = now()`,
+		event.ID, event.Tx.ID(), event.Type, event.StartState, event.FinalState, event.Status, event.RetryN,
+		event.SpanID, event.ModelVersion, event.CompensatedState, pq.Array(event.Compensations), event.Created)
+	if err != nil {
+		return fmt.Errorf("pgrepo update event: %w", err)
+	}
+
+	return nil
+}
+
+// Events история событий транзакции, Event.Tx не заполняется
+func (r *Repository) Events(ctx context.Context, txID uuid.UUID) ([]*model.Event, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT event_id, event_type, start_state, final_state, status, retry_n, span_id,
+    model_version, compensated_state, compensations, created, updated
+FROM fsm_event WHERE tx_id = $1 ORDER BY created, updated`, txID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []*model.Event
+
+	for rows.Next() {
+		ev := &model.Event{}
+
+		err = rows.Scan(&ev.ID, &ev.Type, &ev.StartState, &ev.FinalState, &ev.Status, &ev.RetryN, &ev.SpanID,
+			&ev.ModelVersion, &ev.CompensatedState, pq.Array(&ev.Compensations), &ev.Created, &ev.Updated)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, ev)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *Repository) CountActiveTransactions(ctx context.Context, modelName string, version string) (int, error) {
+	var n int
+
+	err := r.db.QueryRowContext(ctx, `
+SELECT count(*) FROM fsm_tx WHERE model = $1 AND model_version = $2 AND status IN ($3, $4)`,
+		modelName, version, model.TxStatusPending, model.TxStatusProgress).Scan(&n)
+
+	return n, err
+}
+
+func (r *Repository) columns() []string {
+	return append(append([]string(nil), txColumns...), r.mapper.Columns()...)
+}
+
+func (r *Repository) selectColumns() string {
+	return strings.Join(r.columns(), ", ")
+}
+
+// txValues значения колонок транзакции в порядке columns
+func (r *Repository) txValues(tx model.Tx) ([]interface{}, error) {
+	if tx.State() == nil {
+		return nil, fmt.Errorf("pgrepo: tx %s has no state", tx.ID())
+	}
+
+	extra, err := r.mapper.Values(tx)
+	if err != nil {
+		return nil, fmt.Errorf("pgrepo tx mapper values: %w", err)
+	}
+
+	values := []interface{}{
+		tx.ID(),
+		tx.State().Model().Name(),
+		tx.ModelVersion(),
+		tx.State().Name(),
+		tx.Status(),
+		tx.TraceID(),
+		tx.SpanID(),
+		tx.CallbackURL(),
+		tx.ContextData(),
+	}
+
+	return append(values, extra...), nil
+}
+
+// scanTx читает транзакцию, состояние разрешается с учетом версии модели (nil, если не найдено)
+func (r *Repository) scanTx(ctx context.Context, scan func(dest ...interface{}) error) (model.Tx, error) {
+	var (
+		modelName, version, state, status string
+		traceID, spanID, callbackURL      string
+		contextData                       []byte
+	)
+
+	tx := r.mapper.NewTx()
+	id, extra := r.mapper.Dest(tx)
+
+	dest := append([]interface{}{
+		id, &modelName, &version, &state, &status, &traceID, &spanID, &callbackURL, &contextData,
+	}, extra...)
+
+	err := scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	r.m.RLock()
+	resolver := r.resolver
+	r.m.RUnlock()
+
+	if resolver != nil {
+		if s, _ := resolver.ResolveVersion(ctx, state, version); s != nil {
+			tx.SetState(s)
+		}
+	}
+
+	tx.SetModelVersion(version)
+	tx.SetStatus(model.TxStatus(status))
+	tx.SetTraceID(traceID)
+	tx.SetSpanID(spanID)
+	tx.SetCallbackURL(callbackURL)
+	tx.SetContextData(contextData)
+
+	return tx, nil
+}
@@ -0,0 +1,89 @@
+package pgrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+// UpdateTransactionWithRecord обновляет транзакцию и сохраняет запись о переходе в fsm_transition_outbox
+// в одной транзакции БД, повторное сохранение записи с тем же ID игнорируется
+func (r *Repository) UpdateTransactionWithRecord(ctx context.Context, tx model.Tx, currState string,
+	record *model.TransitionRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("pgrepo transition record marshal: %w", err)
+	}
+
+	dbTx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = dbTx.Rollback()
+	}()
+
+	err = r.updateTransaction(ctx, dbTx, tx, currState)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbTx.ExecContext(ctx, `
+INSERT INTO fsm_transition_outbox (id, record) VALUES ($1, $2)
+ON CONFLICT (id) DO NOTHING`, record.ID, body)
+	if err != nil {
+		return fmt.Errorf("pgrepo transition record insertion: %w", err)
+	}
+
+	return dbTx.Commit()
+}
+
+func (r *Repository) PendingTransitionRecords(ctx context.Context, limit int) ([]*model.TransitionRecord, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT record FROM fsm_transition_outbox ORDER BY seq LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("pgrepo transition records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*model.TransitionRecord
+
+	for rows.Next() {
+		var body []byte
+
+		err = rows.Scan(&body)
+		if err != nil {
+			return nil, err
+		}
+
+		record := &model.TransitionRecord{}
+
+		err = json.Unmarshal(body, record)
+		if err != nil {
+			return nil, fmt.Errorf("pgrepo transition record unmarshal: %w", err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func (r *Repository) DeleteTransitionRecords(ctx context.Context, ids []uuid.UUID) error {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, id.String())
+	}
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM fsm_transition_outbox WHERE id = ANY($1::uuid[])`, pq.Array(keys))
+	if err != nil {
+		return fmt.Errorf("pgrepo transition records deletion: %w", err)
+	}
+
+	return nil
+}
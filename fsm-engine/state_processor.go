@@ -2,24 +2,120 @@ package fsmengine
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"fsm-framework/fsm-engine/model"
 	"fsm-framework/fsm-engine/queue"
 	zlog "fsm-framework/misk/logger"
 )
 
+// StateProcessor обработчик очереди состояния, общей для всех версий модели
 type StateProcessor struct {
-	state       model.State
-	consumerCh  queue.Channel
+	queue       string
+	broker      queue.Broker
 	publisherCh queue.Channel
+
+	m sync.RWMutex
+	// consumerCh канал консюмера очереди, пересоздается после приостановки обработки
+	consumerCh queue.Channel
+	// ctx, cfg параметры запущенного консюмера
+	ctx context.Context
+	cfg *pipelineConfig
+	// paused обработка очереди приостановлена (pause), stopped – остановлена окончательно
+	paused  bool
+	stopped bool
+	// states состояния по версиям моделей, события которых обрабатываются из очереди
+	states map[string]model.State
+	// latest состояние последней версии модели, используется для событий без версии
+	latest model.State
+	// breaker circuit breaker очереди по настройкам последней версии модели (может быть nil)
+	breaker *circuitBreaker
+}
+
+func newStateProcessor(queueName string, broker queue.Broker, consumerCh, publisherCh queue.Channel) *StateProcessor {
+	return &StateProcessor{
+		queue:       queueName,
+		broker:      broker,
+		consumerCh:  consumerCh,
+		publisherCh: publisherCh,
+		states:      make(map[string]model.State, 1),
+	}
+}
+
+// addState добавляет состояние очередной версии модели, последнее добавленное считается актуальным
+func (sp *StateProcessor) addState(state model.State) {
+	sp.m.Lock()
+	defer sp.m.Unlock()
+
+	sp.states[state.Model().Version()] = state
+	sp.latest = state
+
+	cfg := state.CircuitBreaker()
+
+	switch {
+	case cfg == nil:
+		sp.breaker = nil
+	case sp.breaker == nil:
+		sp.breaker = newCircuitBreaker(cfg)
+	default:
+		sp.breaker.m.Lock()
+		sp.breaker.cfg = cfg
+		sp.breaker.m.Unlock()
+	}
+}
+
+// removeState удаляет состояние выведенной из эксплуатации версии модели, возвращает кол-во оставшихся версий
+func (sp *StateProcessor) removeState(state model.State) int {
+	sp.m.Lock()
+	defer sp.m.Unlock()
+
+	delete(sp.states, state.Model().Version())
+
+	if sp.latest == state {
+		sp.latest = nil
+	}
+
+	return len(sp.states)
+}
+
+// State состояние указанной версии модели, либо последней версии, если версия не указана
+func (sp *StateProcessor) State(version string) model.State {
+	sp.m.RLock()
+	defer sp.m.RUnlock()
+
+	if version == "" {
+		return sp.latest
+	}
+
+	return sp.states[version]
+}
+
+// circuitBreaker circuit breaker очереди, nil – если не настроен
+func (sp *StateProcessor) circuitBreaker() *circuitBreaker {
+	sp.m.RLock()
+	defer sp.m.RUnlock()
+
+	return sp.breaker
 }
 
 func (sp *StateProcessor) StartConsume(appCtx context.Context, cfg *pipelineConfig) error {
-	ctx := zlog.FromLogger(zlog.Ctx(appCtx).With().Str("state", sp.state.Name()).Logger()).
+	sp.m.Lock()
+	defer sp.m.Unlock()
+
+	sp.ctx = appCtx
+	sp.cfg = cfg
+
+	return sp.consume()
+}
+
+// consume запускает обработку очереди каналом consumerCh, вызывается под sp.m
+func (sp *StateProcessor) consume() error {
+	ctx := zlog.FromLogger(zlog.Ctx(sp.ctx).With().Str("queue", sp.queue).Logger()).
 		WithContext(context.Background())
 
-	err := sp.consumerCh.Consume(appCtx, sp.state.Queue(), func(_ context.Context, d queue.Delivery) error {
-		newProcessPipeline(cfg, sp.state).Process(ctx, d)
+	err := sp.consumerCh.Consume(sp.ctx, sp.queue, func(_ context.Context, d queue.Delivery) error {
+		newProcessPipeline(sp.cfg, sp).Process(ctx, d)
 
 		return nil
 	})
@@ -30,6 +126,85 @@ func (sp *StateProcessor) StartConsume(appCtx context.Context, cfg *pipelineConf
 	return nil
 }
 
+// StopConsume окончательно останавливает обработку очереди, ожидая завершения текущих обработчиков
+func (sp *StateProcessor) StopConsume() error {
+	sp.m.Lock()
+	sp.stopped = true
+	paused, ch := sp.paused, sp.consumerCh
+	sp.m.Unlock()
+
+	// канал приостановленной очереди уже закрыт, консюмер не будет перезапущен
+	if paused {
+		return nil
+	}
+
+	return ch.Close()
+}
+
+// pause приостанавливает обработку очереди на d: канал консюмера закрывается (неподтвержденные сообщения
+// остаются в очереди для других реплик) и спустя d создается заново. Вызывается в том числе из обработчика очереди
+func (sp *StateProcessor) pause(d time.Duration) {
+	sp.m.Lock()
+	if sp.paused || sp.stopped {
+		sp.m.Unlock()
+
+		return
+	}
+
+	sp.paused = true
+	ch := sp.consumerCh
+	sp.m.Unlock()
+
+	go func() {
+		// закрытие канала ожидает завершения обработчиков, в том числе вызвавшего pause
+		if err := ch.Close(); err != nil {
+			zlog.Ctx(sp.ctx).Error().Err(err).Str("queue", sp.queue).Msg("error while pausing consumer in fsm")
+		}
+
+		for {
+			sp.cfg.clock.Sleep(d)
+
+			if sp.resume() {
+				return
+			}
+		}
+	}()
+}
+
+// resume перезапускает обработку приостановленной очереди, false – если консюмер не удалось запустить
+func (sp *StateProcessor) resume() bool {
+	sp.m.Lock()
+	defer sp.m.Unlock()
+
+	if sp.stopped {
+		return true
+	}
+
+	ch, err := sp.broker.Channel()
+	if err == nil {
+		prev := sp.consumerCh
+		sp.consumerCh = ch
+
+		err = sp.consume()
+		if err != nil {
+			_ = ch.Close()
+			sp.consumerCh = prev
+		}
+	}
+
+	if err != nil {
+		zlog.Ctx(sp.ctx).Error().Err(err).Str("queue", sp.queue).Msg("error while resuming consumer in fsm")
+
+		return false
+	}
+
+	sp.paused = false
+
+	zlog.Ctx(sp.ctx).Info().Str("queue", sp.queue).Msg("fsm consumer resumed")
+
+	return true
+}
+
 func (sp *StateProcessor) Publish(ctx context.Context, ev *model.Event) {
 	body, err := model.EventMarshal(ev)
 	if err != nil {
@@ -41,5 +216,5 @@ func (sp *StateProcessor) Publish(ctx context.Context, ev *model.Event) {
 		return
 	}
 
-	sp.publisherCh.Publish(ctx, sp.state.Queue(), body)
+	sp.publisherCh.Publish(ctx, sp.queue, body)
 }
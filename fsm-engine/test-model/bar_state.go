@@ -47,6 +47,18 @@ func (f *BarStateDeclaration) FallbackState() model.State {
 	return nil
 }
 
+func (f *BarStateDeclaration) RateLimit() *model.RateLimit {
+	return nil
+}
+
+func (f *BarStateDeclaration) Backoff() *model.Backoff {
+	return nil
+}
+
+func (f *BarStateDeclaration) CircuitBreaker() *model.CircuitBreaker {
+	return nil
+}
+
 func (f *BarStateDeclaration) IsInitial() bool {
 	return false
 }
@@ -51,6 +51,18 @@ func (f *FooStateDeclaration) FallbackState() model.State {
 	return BarState
 }
 
+func (f *FooStateDeclaration) RateLimit() *model.RateLimit {
+	return nil
+}
+
+func (f *FooStateDeclaration) Backoff() *model.Backoff {
+	return nil
+}
+
+func (f *FooStateDeclaration) CircuitBreaker() *model.CircuitBreaker {
+	return nil
+}
+
 func (f *FooStateDeclaration) IsInitial() bool {
 	return true
 }
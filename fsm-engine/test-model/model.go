@@ -15,6 +15,10 @@ func (m *ModelDeclaration) Name() string {
 	return "test"
 }
 
+func (m *ModelDeclaration) Version() string {
+	return "1"
+}
+
 func (m *ModelDeclaration) States() []model.State {
 	return []model.State{
 		FooState,
@@ -67,3 +71,7 @@ func (m *ModelDeclaration) SetService(svc interface{}) error {
 func (m *ModelDeclaration) Service() interface{} {
 	return m.svc
 }
+
+func (m *ModelDeclaration) NewContextData() interface{} {
+	return nil
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"fsm-framework/fsm-engine/genmocks/mocks"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/repository/memrepo"
 
 	fsmengine "fsm-framework/fsm-engine"
 )
@@ -53,13 +55,14 @@ func TestInit(t *testing.T) {
 	cm.On("Send", mock.Anything, mock.Anything, mock.Anything).
 		Return()
 
-	engine := fsmengine.New(fsmengine.Config{
+	engine, err := fsmengine.New(fsmengine.Config{
 		Repository:      repo,
 		Locker:          locker,
 		Broker:          broker,
 		CallbackManager: cm,
 		VerboseTracing:  false,
 	})
+	assert.NoError(t, err, "engine creation error")
 
 	// add model
 	err = Model.SetService(svc)
@@ -73,9 +76,56 @@ func TestInit(t *testing.T) {
 	assert.Equal(t, Model, foundM, "engine model resolving error")
 
 	// new tx
-	assert.NoError(t, engine.CreateTx(ctx, tx, FooState))
-	assert.Error(t, engine.CreateTx(ctx, tx, BarState))
+	_, err = engine.CreateTx(ctx, tx, FooState)
+	assert.NoError(t, err)
+	_, err = engine.CreateTx(ctx, tx, BarState)
+	assert.Error(t, err)
 
 	// transit
 	assert.NoError(t, engine.Transit(ctx, tx, BarState))
 }
+
+// Параллельный переход из одного состояния проходит только один раз
+func TestTransitConflict(t *testing.T) {
+	ctx := context.TODO()
+
+	repo := memrepo.New(func(tx model.Tx) model.Tx {
+		cp := *tx.(*testTx)
+
+		return &cp
+	})
+
+	qChan := &mocks.QueueChannelMock{}
+	qChan.On("Consume", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	qChan.On("Publish", mock.Anything, mock.Anything, mock.Anything).
+		Return()
+
+	broker := &mocks.QueueBrokerMock{}
+	broker.On("Channel").
+		Return(qChan, nil)
+
+	engine, err := fsmengine.New(fsmengine.Config{
+		Repository:      repo,
+		Locker:          &mocks.LockLockerMock{},
+		Broker:          broker,
+		CallbackManager: &mocks.CallbackManagerMock{},
+	})
+	assert.NoError(t, err, "engine creation error")
+
+	assert.NoError(t, Model.SetService(&mocks.TestServiceMock{}))
+	assert.NoError(t, engine.AddModel(ctx, Model))
+
+	tx := &testTx{TxID: uuid.New()}
+	_, err = engine.CreateTx(ctx, tx, FooState)
+	assert.NoError(t, err)
+
+	first, err := repo.Transaction(ctx, tx.ID())
+	assert.NoError(t, err)
+	second, err := repo.Transaction(ctx, tx.ID())
+	assert.NoError(t, err)
+
+	assert.NoError(t, engine.Transit(ctx, first, BarState))
+	assert.Error(t, engine.Transit(ctx, second, BarState))
+	assert.Equal(t, []string{FooState.Name(), BarState.Name()}, repo.Path(tx.ID()))
+}
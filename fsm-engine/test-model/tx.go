@@ -15,6 +15,8 @@ type testTx struct {
 	TxCallbackURL string
 	TxTraceID     string
 	TxSpanID      string
+	TxVersion     string
+	TxData        []byte
 }
 
 func (t *testTx) ID() uuid.UUID {
@@ -60,3 +62,19 @@ func (t *testTx) CallbackURL() string {
 func (t *testTx) SetCallbackURL(callbackURL string) {
 	t.TxCallbackURL = callbackURL
 }
+
+func (t *testTx) ModelVersion() string {
+	return t.TxVersion
+}
+
+func (t *testTx) SetModelVersion(version string) {
+	t.TxVersion = version
+}
+
+func (t *testTx) ContextData() []byte {
+	return t.TxData
+}
+
+func (t *testTx) SetContextData(data []byte) {
+	t.TxData = data
+}
@@ -0,0 +1,180 @@
+package fsmengine_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+// testModel модель, собираемая в тестах движка вместо сгенерированной
+type testModel struct {
+	name    string
+	version string
+	states  []model.State
+	engine  model.Engine
+	// newData конструктор контекстных данных модели (может быть nil)
+	newData func() interface{}
+}
+
+func newTestModel(name, version string) *testModel {
+	return &testModel{name: name, version: version}
+}
+
+// state добавляет состояние модели, next – разрешенные переходы (по названию)
+func (m *testModel) state(name string, next ...string) *testState {
+	s := &testState{name: name, model: m, next: next}
+	m.states = append(m.states, s)
+
+	return s
+}
+
+// compensated добавляет состояние с компенсирующим обработчиком
+func (m *testModel) compensated(name string, compensate func(ctx context.Context, ev *model.Event) error,
+	next ...string) *compensatedState {
+	s := &compensatedState{
+		testState:  testState{name: name, model: m, next: next},
+		compensate: compensate,
+	}
+	m.states = append(m.states, s)
+
+	return s
+}
+
+func (m *testModel) Name() string    { return m.name }
+func (m *testModel) Version() string { return m.version }
+
+func (m *testModel) States() []model.State {
+	return m.states
+}
+
+func (m *testModel) Resolve(name string) model.State {
+	for _, s := range m.states {
+		if s.Name() == name {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (m *testModel) Has(state model.State) bool {
+	for _, s := range m.states {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *testModel) SetEngine(engine model.Engine)    { m.engine = engine }
+func (m *testModel) Engine() model.Engine             { return m.engine }
+func (m *testModel) SetService(svc interface{}) error { return nil }
+func (m *testModel) Service() interface{}             { return nil }
+
+func (m *testModel) NewContextData() interface{} {
+	if m.newData == nil {
+		return nil
+	}
+
+	return m.newData()
+}
+
+// testState состояние тестовой модели, по умолчанию обработчик завершает обработку транзакции
+type testState struct {
+	name    string
+	model   *testModel
+	next    []string
+	initial bool
+	success bool
+	fail    bool
+	// fallback состояние после исчерпания попыток (по названию)
+	fallback string
+	breaker  *model.CircuitBreaker
+	handler  func(ctx context.Context, ev *model.Event) model.State
+}
+
+func (s *testState) Name() string      { return s.name }
+func (s *testState) EventType() string { return s.model.name + "_" + s.name + "_event" }
+func (s *testState) Queue() string     { return s.model.name + "_" + s.name + "_event_queue" }
+
+func (s *testState) EventHandler(ctx context.Context, ev *model.Event) model.State {
+	if s.handler == nil {
+		return nil
+	}
+
+	return s.handler(ctx, ev)
+}
+
+func (s *testState) CanTransitIn(state model.State) bool {
+	if state == model.State(s) {
+		return true
+	}
+
+	for _, name := range s.next {
+		if s.model.Resolve(name) == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *testState) MaxRetiesCount() int                   { return model.EventRetryMaxCount }
+func (s *testState) MinRetiesDelay() time.Duration         { return model.EventRetryMinDelay }
+func (s *testState) CancellationTTL() time.Duration        { return time.Minute }
+func (s *testState) RateLimit() *model.RateLimit           { return nil }
+func (s *testState) Backoff() *model.Backoff               { return nil }
+func (s *testState) CircuitBreaker() *model.CircuitBreaker { return s.breaker }
+func (s *testState) IsInitial() bool                       { return s.initial }
+func (s *testState) IsSuccessFinal() bool                  { return s.success }
+func (s *testState) IsFailFinal() bool                     { return s.fail }
+func (s *testState) Model() model.Model                    { return s.model }
+
+func (s *testState) FallbackState() model.State {
+	if s.fallback == "" {
+		return nil
+	}
+
+	return s.model.Resolve(s.fallback)
+}
+
+// to обработчик, переводящий транзакцию в состояние name той же версии модели
+func (s *testState) to(name string) *testState {
+	s.handler = func(ctx context.Context, ev *model.Event) model.State {
+		return s.model.Resolve(name)
+	}
+
+	return s
+}
+
+type compensatedState struct {
+	testState
+	compensate func(ctx context.Context, ev *model.Event) error
+}
+
+func (s *compensatedState) Compensate(ctx context.Context, ev *model.Event) error {
+	return s.compensate(ctx, ev)
+}
+
+// recorder потокобезопасный журнал вызовов обработчиков
+type recorder struct {
+	m     sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) list() []string {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return append([]string(nil), r.calls...)
+}
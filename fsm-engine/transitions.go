@@ -0,0 +1,276 @@
+package fsmengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/queue"
+	zlog "fsm-framework/misk/logger"
+)
+
+const (
+	// defaultRelayInterval период проверки outbox на случай, если уведомление о новой записи не было получено
+	// (запись сохранена другой репликой или предыдущая публикация не удалась)
+	defaultRelayInterval = time.Second
+	// defaultRelayBatch количество записей, вычитываемых из outbox за раз
+	defaultRelayBatch = 100
+)
+
+// TransitionPublisher публикует записи о зафиксированных переходах транзакций (model.TransitionRecord)
+// в отдельную очередь, на которую могут подписываться другие сервисы.
+// Движок сохраняет запись в outbox своего репозитория (model.TransitionRepository) вместе с обновлением транзакции,
+// публикатор пересылает записи из outbox в очередь и удаляет их только после того, как брокер их принял
+type TransitionPublisher struct {
+	ch    queue.Channel
+	queue string
+	ctx   context.Context
+
+	// repo outbox репозитория движка, задается при создании движка (Config.Transitions)
+	repo model.TransitionRepository
+
+	interval time.Duration
+	batch    int
+
+	// relayM не дает одновременно пересылать одни и те же записи
+	relayM sync.Mutex
+	// notify сигнал о новой записи в outbox, stop и done остановка пересылки
+	notify    chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// TransitionOption параметр публикатора записей о переходах
+type TransitionOption func(tp *TransitionPublisher)
+
+// WithRelayInterval период проверки outbox (по умолчанию секунда)
+func WithRelayInterval(interval time.Duration) TransitionOption {
+	return func(tp *TransitionPublisher) {
+		tp.interval = interval
+	}
+}
+
+// WithRelayBatch количество записей, вычитываемых из outbox за раз (по умолчанию 100)
+func WithRelayBatch(batch int) TransitionOption {
+	return func(tp *TransitionPublisher) {
+		tp.batch = batch
+	}
+}
+
+// NewTransitionPublisher создает канал брокера и объявляет очередь для записей о переходах.
+// Пересылка записей из outbox запускается движком, в конфигурации которого передан публикатор (Config.Transitions)
+func NewTransitionPublisher(ctx context.Context, broker queue.Broker, queueName string,
+	opts ...TransitionOption) (*TransitionPublisher, error) {
+	ch, err := broker.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("transition channel creation error: %w", err)
+	}
+
+	err = ch.DeclareQueue(queueName)
+	if err != nil {
+		return nil, fmt.Errorf("transition queue declaration error: %w", err)
+	}
+
+	tp := &TransitionPublisher{
+		ch:       ch,
+		queue:    queueName,
+		ctx:      ctx,
+		interval: defaultRelayInterval,
+		batch:    defaultRelayBatch,
+		notify:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(tp)
+	}
+
+	return tp, nil
+}
+
+// start запускает пересылку записей из outbox репозитория движка, начиная с оставшихся от предыдущего запуска
+func (tp *TransitionPublisher) start(repo model.TransitionRepository) error {
+	tp.relayM.Lock()
+	defer tp.relayM.Unlock()
+
+	if tp.repo != nil {
+		return errors.New("transition publisher is already used by another engine")
+	}
+
+	tp.repo = repo
+
+	go tp.run()
+
+	tp.Notify()
+
+	return nil
+}
+
+// Notify сообщает о новой записи в outbox, пересылка начинается не дожидаясь очередной проверки
+func (tp *TransitionPublisher) Notify() {
+	select {
+	case tp.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Relay пересылает в очередь все записи outbox в порядке сохранения. При ошибке публикации пересылка
+// прерывается, неопубликованные записи остаются в outbox до следующего вызова
+func (tp *TransitionPublisher) Relay(ctx context.Context) error {
+	tp.relayM.Lock()
+	defer tp.relayM.Unlock()
+
+	if tp.repo == nil {
+		return errors.New("transition publisher is not started by engine")
+	}
+
+	for {
+		records, err := tp.repo.PendingTransitionRecords(ctx, tp.batch)
+		if err != nil {
+			return fmt.Errorf("pending transition records: %w", err)
+		}
+
+		if len(records) == 0 {
+			return nil
+		}
+
+		published := make([]uuid.UUID, 0, len(records))
+
+		var publishErr error
+
+		for _, record := range records {
+			publishErr = tp.publish(ctx, record)
+			if publishErr != nil {
+				break
+			}
+
+			published = append(published, record.ID)
+		}
+
+		if len(published) > 0 {
+			err = tp.repo.DeleteTransitionRecords(ctx, published)
+			if err != nil {
+				return fmt.Errorf("published transition records deletion: %w", err)
+			}
+		}
+
+		if publishErr != nil {
+			return fmt.Errorf("transition record publish: %w", publishErr)
+		}
+
+		if len(records) < tp.batch {
+			return nil
+		}
+	}
+}
+
+// publish публикует запись, ошибка возвращается, только если канал брокера реализует queue.ConfirmChannel
+func (tp *TransitionPublisher) publish(ctx context.Context, record *model.TransitionRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("transition record marshal: %w", err)
+	}
+
+	if ch, ok := tp.ch.(queue.ConfirmChannel); ok {
+		return ch.PublishConfirm(ctx, tp.queue, body)
+	}
+
+	tp.ch.Publish(ctx, tp.queue, body)
+
+	return nil
+}
+
+func (tp *TransitionPublisher) run() {
+	defer close(tp.done)
+
+	ticker := time.NewTicker(tp.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tp.stop:
+			return
+		case <-tp.notify:
+		case <-ticker.C:
+		}
+
+		err := tp.Relay(tp.ctx)
+		if err != nil {
+			zlog.Ctx(tp.ctx).Error().Err(err).Msg("transition records relay error")
+		}
+	}
+}
+
+// Close останавливает пересылку, пересылает оставшиеся записи и закрывает канал публикации
+func (tp *TransitionPublisher) Close() error {
+	tp.relayM.Lock()
+	started := tp.repo != nil
+	tp.relayM.Unlock()
+
+	tp.closeOnce.Do(func() {
+		close(tp.stop)
+	})
+
+	if started {
+		<-tp.done
+
+		err := tp.Relay(tp.ctx)
+		if err != nil {
+			zlog.Ctx(tp.ctx).Error().Err(err).Msg("transition records relay error")
+		}
+	}
+
+	return tp.ch.Close()
+}
+
+// saveTransition сохраняет транзакцию, переведенную из fromState вне обработки события (ручной переход, миграция).
+// Если публикация переходов настроена, запись о переходе сохраняется атомарно с транзакцией
+func (e *Engine) saveTransition(ctx context.Context, tx model.Tx, fromState string) error {
+	if e.transitions == nil {
+		return e.repo.UpdateTransaction(ctx, tx, fromState)
+	}
+
+	record := &model.TransitionRecord{
+		Version:      model.TransitionRecordVersion,
+		ID:           uuid.New(),
+		TxID:         tx.ID(),
+		Model:        tx.State().Model().Name(),
+		ModelVersion: tx.ModelVersion(),
+		FromState:    fromState,
+		ToState:      tx.State().Name(),
+		TxStatus:     tx.Status(),
+		TraceID:      tx.TraceID(),
+		TraceContext: map[string]string{},
+		Created:      e.clock.Now(),
+	}
+
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if spanCtx, ok := span.Context().(jaeger.SpanContext); ok {
+			record.SpanID = spanCtx.SpanID().String()
+		}
+
+		err := span.Tracer().Inject(span.Context(), opentracing.TextMap, opentracing.TextMapCarrier(record.TraceContext))
+		if err != nil {
+			zlog.Ctx(ctx).Warn().Err(err).Msg("can't inject trace context into transition record")
+		}
+	}
+
+	err := e.transitionRepo.UpdateTransactionWithRecord(ctx, tx, fromState, record)
+	if err != nil {
+		return err
+	}
+
+	e.transitions.Notify()
+
+	return nil
+}
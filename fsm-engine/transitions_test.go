@@ -0,0 +1,312 @@
+package fsmengine_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	fsmengine "fsm-framework/fsm-engine"
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/lock/maplock"
+	"fsm-framework/fsm-engine/model"
+	"fsm-framework/fsm-engine/queue"
+	"fsm-framework/fsm-engine/queue/memqueue"
+	"fsm-framework/fsm-engine/repository/memrepo"
+)
+
+const transitionsQueue = "fsm_transitions"
+
+var errBrokerDown = errors.New("broker is down")
+
+// downBroker брокер записей о переходах, перестающий принимать сообщения по флагу down
+type downBroker struct {
+	*memqueue.Broker
+	down atomic.Bool
+}
+
+func (b *downBroker) Channel() (queue.Channel, error) {
+	ch, err := b.Broker.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return &downChannel{ConfirmChannel: ch.(queue.ConfirmChannel), broker: b}, nil
+}
+
+type downChannel struct {
+	queue.ConfirmChannel
+	broker *downBroker
+}
+
+func (c *downChannel) PublishConfirm(ctx context.Context, queueName string, body []byte) error {
+	if c.broker.down.Load() {
+		return errBrokerDown
+	}
+
+	return c.ConfirmChannel.PublishConfirm(ctx, queueName, body)
+}
+
+type nopCallbacks struct{}
+
+func (nopCallbacks) Send(ctx context.Context, tx model.Tx) {}
+func (nopCallbacks) Stop() error                           { return nil }
+
+// transitionsModel CREATED -> DONE
+func transitionsModel() *testModel {
+	m := newTestModel("transitions", "1")
+
+	created := m.state("CREATED", "DONE").to("DONE")
+	created.initial = true
+
+	m.state("DONE").success = true
+
+	return m
+}
+
+// startEngine запускает движок над общим репозиторием, записи о переходах публикуются через transitions
+func startEngine(t *testing.T, repo *memrepo.Repository, transitions *fsmengine.TransitionPublisher,
+	mdl model.Model) *fsmengine.Engine {
+	t.Helper()
+
+	locker, err := maplock.NewLocker()
+	require.NoError(t, err)
+
+	engine, err := fsmengine.New(fsmengine.Config{
+		Repository:      repo,
+		Locker:          locker,
+		Broker:          memqueue.NewBroker(),
+		CallbackManager: nopCallbacks{},
+		Transitions:     transitions,
+	})
+	require.NoError(t, err)
+	require.NoError(t, engine.AddModel(context.Background(), mdl))
+
+	return engine
+}
+
+func waitDone(t *testing.T, repo *memrepo.Repository, tx model.Tx) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		stored, err := repo.Transaction(context.Background(), tx.ID())
+		require.NoError(t, err)
+
+		return stored.Status() == model.TxStatusDone
+	}, 5*time.Second, time.Millisecond)
+}
+
+// consumeRecords вычитывает записи о переходах из очереди
+func consumeRecords(t *testing.T, broker queue.Broker) func() []*model.TransitionRecord {
+	t.Helper()
+
+	ch, err := broker.Channel()
+	require.NoError(t, err)
+	require.NoError(t, ch.DeclareQueue(transitionsQueue))
+
+	var (
+		m       sync.Mutex
+		records []*model.TransitionRecord
+	)
+
+	err = ch.Consume(context.Background(), transitionsQueue, func(ctx context.Context, d queue.Delivery) error {
+		record := &model.TransitionRecord{}
+		require.NoError(t, json.Unmarshal(d.GetBody(), record))
+
+		m.Lock()
+		records = append(records, record)
+		m.Unlock()
+
+		d.Ack(ctx)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = ch.Close() })
+
+	return func() []*model.TransitionRecord {
+		m.Lock()
+		defer m.Unlock()
+
+		return append([]*model.TransitionRecord(nil), records...)
+	}
+}
+
+func assertRecords(t *testing.T, tx model.Tx, records []*model.TransitionRecord) {
+	t.Helper()
+
+	require.Len(t, records, 2)
+
+	assert.Equal(t, tx.ID(), records[0].TxID)
+	assert.Equal(t, "CREATED", records[0].FromState)
+	assert.Equal(t, "DONE", records[0].ToState)
+	assert.False(t, records[0].Final)
+
+	assert.Equal(t, tx.ID(), records[1].TxID)
+	assert.Equal(t, "DONE", records[1].FromState)
+	assert.Empty(t, records[1].ToState)
+	assert.True(t, records[1].Final)
+	assert.Equal(t, model.TxStatusDone, records[1].TxStatus)
+
+	assert.NotEqual(t, records[0].ID, records[1].ID)
+}
+
+func TestTransitionRecordsPublished(t *testing.T) {
+	ctx := context.Background()
+	mdl := transitionsModel()
+	repo := memrepo.New(fsmtest.CloneTx)
+	broker := memqueue.NewBroker()
+	received := consumeRecords(t, broker)
+
+	// запись пересылается по уведомлению, не дожидаясь периодической проверки
+	transitions, err := fsmengine.NewTransitionPublisher(ctx, broker, transitionsQueue,
+		fsmengine.WithRelayInterval(time.Hour))
+	require.NoError(t, err)
+
+	engine := startEngine(t, repo, transitions, mdl)
+	defer engine.Stop(ctx)
+
+	tx, err := engine.CreateTx(ctx, fsmtest.NewTx(), mdl.Resolve("CREATED"))
+	require.NoError(t, err)
+	waitDone(t, repo, tx)
+
+	require.Eventually(t, func() bool {
+		return len(received()) == 2
+	}, 5*time.Second, time.Millisecond)
+
+	assertRecords(t, tx, received())
+
+	pending, err := repo.PendingTransitionRecords(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestTransitionRecordsSurviveKill(t *testing.T) {
+	ctx := context.Background()
+	mdl := transitionsModel()
+	repo := memrepo.New(fsmtest.CloneTx)
+	broker := memqueue.NewBroker()
+
+	// реплика останавливается после фиксации переходов, но до того, как брокер принял записи
+	down := &downBroker{Broker: broker}
+	down.down.Store(true)
+
+	transitions, err := fsmengine.NewTransitionPublisher(ctx, down, transitionsQueue)
+	require.NoError(t, err)
+
+	engine := startEngine(t, repo, transitions, mdl)
+
+	tx, err := engine.CreateTx(ctx, fsmtest.NewTx(), mdl.Resolve("CREATED"))
+	require.NoError(t, err)
+	waitDone(t, repo, tx)
+
+	engine.Stop(ctx)
+
+	assert.Zero(t, broker.Stats(transitionsQueue).Published)
+
+	pending, err := repo.PendingTransitionRecords(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	// после перезапуска записи пересылаются из outbox, не дожидаясь периодической проверки
+	received := consumeRecords(t, broker)
+
+	restarted, err := fsmengine.NewTransitionPublisher(ctx, broker, transitionsQueue,
+		fsmengine.WithRelayInterval(time.Hour))
+	require.NoError(t, err)
+
+	engine = startEngine(t, repo, restarted, mdl)
+	defer engine.Stop(ctx)
+
+	require.Eventually(t, func() bool {
+		return len(received()) == 2
+	}, 5*time.Second, time.Millisecond)
+
+	assertRecords(t, tx, received())
+
+	pending, err = repo.PendingTransitionRecords(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestTransitRecord(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.New(fsmtest.CloneTx)
+	broker := memqueue.NewBroker()
+	received := consumeRecords(t, broker)
+
+	// CREATED -> WAIT -> DONE, из WAIT транзакция переводится вручную
+	mdl := newTestModel("manual", "1")
+	created := mdl.state("CREATED", "WAIT").to("WAIT")
+	created.initial = true
+	mdl.state("WAIT", "DONE")
+	mdl.state("DONE").success = true
+
+	transitions, err := fsmengine.NewTransitionPublisher(ctx, broker, transitionsQueue,
+		fsmengine.WithRelayInterval(time.Hour))
+	require.NoError(t, err)
+
+	engine := startEngine(t, repo, transitions, mdl)
+	defer engine.Stop(ctx)
+
+	tx := fsmtest.NewTx()
+	tx.SetState(mdl.Resolve("WAIT"))
+	tx.SetStatus(model.TxStatusPending)
+	tx.SetModelVersion("1")
+	require.NoError(t, repo.CreateTransaction(ctx, tx))
+
+	require.NoError(t, engine.Transit(ctx, tx, mdl.Resolve("DONE")))
+	waitDone(t, repo, tx)
+
+	// ручной переход и завершение транзакции в DONE
+	require.Eventually(t, func() bool {
+		return len(received()) == 2
+	}, 5*time.Second, time.Millisecond)
+
+	records := received()
+
+	assert.Equal(t, tx.ID(), records[0].TxID)
+	assert.Equal(t, "manual", records[0].Model)
+	assert.Equal(t, "WAIT", records[0].FromState)
+	assert.Equal(t, "DONE", records[0].ToState)
+	assert.False(t, records[0].Final)
+
+	assert.Equal(t, "DONE", records[1].FromState)
+	assert.True(t, records[1].Final)
+	assert.NotEqual(t, records[0].ID, records[1].ID)
+
+	pending, err := repo.PendingTransitionRecords(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestTransitionsRequireTransitionRepository(t *testing.T) {
+	ctx := context.Background()
+	broker := memqueue.NewBroker()
+
+	transitions, err := fsmengine.NewTransitionPublisher(ctx, broker, transitionsQueue)
+	require.NoError(t, err)
+
+	defer transitions.Close()
+
+	locker, err := maplock.NewLocker()
+	require.NoError(t, err)
+
+	// записи о переходах не могут быть сохранены атомарно с транзакцией
+	_, err = fsmengine.New(fsmengine.Config{
+		Repository:      struct{ model.Repository }{memrepo.New(fsmtest.CloneTx)},
+		Locker:          locker,
+		Broker:          broker,
+		CallbackManager: nopCallbacks{},
+		Transitions:     transitions,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model.TransitionRepository")
+}
@@ -0,0 +1,105 @@
+package fsmengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	zlog "fsm-framework/misk/logger"
+
+	"fsm-framework/fsm-engine/model"
+)
+
+// RetireVersions выводит из эксплуатации устаревшие версии моделей, на которые не ссылается ни одна
+// обрабатываемая транзакция (model.VersionRepository): останавливает обработку очередей состояний,
+// которых нет в других версиях
+func (e *Engine) RetireVersions(ctx context.Context) error {
+	repo, ok := e.repo.(model.VersionRepository)
+	if !ok {
+		return errors.New("repository doesn't implement model.VersionRepository, versions can't be retired")
+	}
+
+	for _, mdl := range e.outdatedModels() {
+		count, err := repo.CountActiveTransactions(ctx, mdl.Name(), mdl.Version())
+		if err != nil {
+			return fmt.Errorf("count active transactions of %s v.%s: %w", mdl.Name(), mdl.Version(), err)
+		}
+
+		if count > 0 {
+			zlog.Ctx(ctx).Debug().
+				Str("model", mdl.Name()).
+				Str("model_version", mdl.Version()).
+				Int("active_tx", count).
+				Msg("fsm model version still in use")
+
+			continue
+		}
+
+		e.removeModel(ctx, mdl)
+	}
+
+	return nil
+}
+
+// outdatedModels список моделей, для которых инициализирована более новая версия
+func (e *Engine) outdatedModels() []model.Model {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	latest := make(map[string]model.Model, len(e.models))
+	for _, mdl := range e.models {
+		latest[mdl.Name()] = mdl
+	}
+
+	var outdated []model.Model
+
+	for _, mdl := range e.models {
+		if latest[mdl.Name()] != mdl {
+			outdated = append(outdated, mdl)
+		}
+	}
+
+	return outdated
+}
+
+// removeModel удаляет модель из движка, закрывая очереди, которые больше не обрабатываются ни одной версией
+func (e *Engine) removeModel(ctx context.Context, oldModel model.Model) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	for _, s := range oldModel.States() {
+		sp, ok := e.states[s]
+		if !ok {
+			continue
+		}
+
+		delete(e.states, s)
+
+		if sp.removeState(s) > 0 {
+			continue
+		}
+
+		delete(e.queues, sp.queue)
+
+		if err := sp.StopConsume(); err != nil {
+			zlog.Ctx(ctx).Error().Err(err).Str("queue", sp.queue).Msg("error while stopping consumer in fsm")
+		}
+
+		if err := sp.publisherCh.Close(); err != nil {
+			zlog.Ctx(ctx).Error().Err(err).Str("queue", sp.queue).Msg("error while closing publisher in fsm")
+		}
+	}
+
+	for i, mdl := range e.models {
+		if mdl == oldModel {
+			e.models = append(e.models[:i], e.models[i+1:]...)
+
+			break
+		}
+	}
+
+	zlog.Ctx(ctx).Info().
+		Str("model", oldModel.Name()).
+		Str("model_version", oldModel.Version()).
+		Msg("fsm model version retired")
+}
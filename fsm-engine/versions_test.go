@@ -0,0 +1,118 @@
+package fsmengine_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"fsm-framework/fsm-engine/fsmtest"
+	"fsm-framework/fsm-engine/model"
+)
+
+// versionedModel CREATED -> SECOND, обработчики пишут в журнал версию модели
+func versionedModel(version string, calls *recorder, gate <-chan struct{}) *testModel {
+	m := newTestModel("versioned", version)
+
+	created := m.state("CREATED", "SECOND")
+	created.initial = true
+	created.handler = func(ctx context.Context, ev *model.Event) model.State {
+		if gate != nil {
+			<-gate
+		}
+
+		calls.add(version + ":CREATED")
+
+		return m.Resolve("SECOND")
+	}
+
+	second := m.state("SECOND")
+	second.success = true
+	second.handler = func(ctx context.Context, ev *model.Event) model.State {
+		calls.add(version + ":SECOND")
+
+		return nil
+	}
+
+	return m
+}
+
+func TestVersionRoutingAndRetirement(t *testing.T) {
+	ctx := context.Background()
+	calls := &recorder{}
+	gate := make(chan struct{})
+
+	v1 := versionedModel("1", calls, gate)
+	v2 := versionedModel("2", calls, nil)
+
+	h := fsmtest.New(t, fsmtest.CloneTx, v1)
+
+	// транзакция начата в v1, ее событие обрабатывается, пока добавляется v2
+	tx1 := h.CreateTx(fsmtest.NewTx(), v1.Resolve("CREATED"))
+	require.NoError(t, h.Engine.AddModel(ctx, v2))
+
+	// незавершенная транзакция удерживает v1
+	require.NoError(t, h.Engine.RetireVersions(ctx))
+
+	state, _ := h.Engine.ResolveVersion(ctx, "CREATED", "1")
+	assert.NotNil(t, state, "v1 retired with active tx")
+
+	close(gate)
+	h.RunUntilIdle()
+
+	assert.Equal(t, []string{"1:CREATED", "1:SECOND"}, calls.list())
+	assert.Equal(t, "1", h.Tx(tx1).ModelVersion())
+	assert.Same(t, v1.Resolve("SECOND"), h.Tx(tx1).State())
+	h.AssertStatus(tx1, model.TxStatusDone)
+
+	// новые транзакции создаются в последней версии
+	tx2 := h.CreateTx(fsmtest.NewTx(), v1.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	assert.Equal(t, []string{"1:CREATED", "1:SECOND", "2:CREATED", "2:SECOND"}, calls.list())
+	assert.Equal(t, "2", h.Tx(tx2).ModelVersion())
+
+	// активных транзакций v1 не осталось
+	require.NoError(t, h.Engine.RetireVersions(ctx))
+
+	state, _ = h.Engine.ResolveVersion(ctx, "CREATED", "1")
+	assert.Nil(t, state, "v1 not retired")
+
+	// общие очереди продолжают обрабатываться v2
+	tx3 := h.CreateTx(fsmtest.NewTx(), v2.Resolve("CREATED"))
+	h.RunUntilIdle()
+
+	h.AssertPath(tx3, v2.Resolve("CREATED"), v2.Resolve("SECOND"))
+	h.AssertStatus(tx3, model.TxStatusDone)
+}
+
+func TestRetireVersionWithErrorTx(t *testing.T) {
+	ctx := context.Background()
+
+	// в v1 обработчик всегда падает, транзакция остается в статусе error
+	v1 := newTestModel("versioned", "1")
+	broken := v1.state("CREATED")
+	broken.initial = true
+	broken.handler = func(ctx context.Context, ev *model.Event) model.State {
+		panic("handler failure")
+	}
+
+	h := fsmtest.New(t, fsmtest.CloneTx, v1)
+
+	tx := h.CreateTx(fsmtest.NewTx(), v1.Resolve("CREATED"))
+	h.RunUntilIdle()
+	h.AssertStatus(tx, model.TxStatusError)
+
+	require.NoError(t, h.Engine.AddModel(ctx, versionedModel("2", &recorder{}, nil)))
+	require.NoError(t, h.Engine.RetireVersions(ctx))
+
+	state, _ := h.Engine.ResolveVersion(ctx, "CREATED", "1")
+	assert.Nil(t, state, "v1 held by error tx")
+}
+
+func TestRetireVersionsRequiresVersionRepository(t *testing.T) {
+	err := basicEngine(t).RetireVersions(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model.VersionRepository")
+}
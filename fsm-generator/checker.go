@@ -0,0 +1,387 @@
+package fsm_generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCheckPaths ограничение количества перечисляемых путей от начальных состояний до конечных
+const maxCheckPaths = 1000
+
+// EdgeKind вид ребра графа модели
+type EdgeKind string
+
+const (
+	// EdgeTransition переход, объявленный в модели
+	EdgeTransition EdgeKind = "transition"
+	// EdgeFallback переход в fallback состояние при исчерпании попыток (добавляется ParseModel)
+	EdgeFallback EdgeKind = "fallback"
+	// EdgeRetry повтор обработки события состояния
+	EdgeRetry EdgeKind = "retry"
+)
+
+// IssueKind вид проблемы графа модели
+type IssueKind string
+
+const (
+	// IssueUnreachable состояние недостижимо из начальных состояний
+	IssueUnreachable IssueKind = "unreachable"
+	// IssueNoPathToFinal из состояния нельзя попасть ни в одно конечное состояние
+	IssueNoPathToFinal IssueKind = "no_path_to_final"
+	// IssueCycleWithoutExit из цикла нет перехода наружу (кроме fallback при исчерпании попыток)
+	IssueCycleWithoutExit IssueKind = "cycle_without_exit"
+	// IssueFinalWithTransitions у конечного состояния объявлены переходы
+	IssueFinalWithTransitions IssueKind = "final_with_transitions"
+)
+
+type CheckEdge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+}
+
+type CheckIssue struct {
+	Kind    IssueKind `json:"kind"`
+	States  []string  `json:"states"`
+	Message string    `json:"message"`
+}
+
+// CheckReport результат исследования графа модели
+type CheckReport struct {
+	Model  string      `json:"model"`
+	ETag   string      `json:"etag"`
+	States []string    `json:"states"`
+	Edges  []CheckEdge `json:"edges"`
+	// Paths все пути без повторов состояний от начальных состояний до конечных
+	Paths [][]string `json:"paths"`
+	// PathsTruncated путей больше maxCheckPaths, перечислены не все
+	PathsTruncated bool         `json:"paths_truncated,omitempty"`
+	Issues         []CheckIssue `json:"issues"`
+	// Drift расхождения сгенерированного кода с моделью (заполняется CheckModels)
+	Drift []*Drift `json:"drift,omitempty"`
+}
+
+// OK проблем не найдено
+func (r *CheckReport) OK() bool {
+	return len(r.Issues) == 0 && len(r.Drift) == 0
+}
+
+// Check исследует все пути графа модели, включая повторы и fallback переходы, добавленные ParseModel
+func (m *Model) Check() *CheckReport {
+	report := &CheckReport{
+		Model:  m.Name,
+		ETag:   m.ETag,
+		States: make([]string, 0, len(m.States)),
+		Paths:  [][]string{},
+		Issues: []CheckIssue{},
+	}
+
+	for _, state := range m.States {
+		report.States = append(report.States, state.Name)
+
+		if !state.isFinal() {
+			report.Edges = append(report.Edges, CheckEdge{From: state.Name, To: state.Name, Kind: EdgeRetry})
+		}
+
+		for _, transition := range state.Transitions {
+			kind := EdgeTransition
+
+			if transition.Fallback {
+				kind = EdgeFallback
+			}
+
+			report.Edges = append(report.Edges, CheckEdge{From: state.Name, To: transition.StateName, Kind: kind})
+		}
+	}
+
+	reachable := m.reachable()
+
+	var unreachable []string
+
+	for _, state := range m.States {
+		if !reachable[state] {
+			unreachable = append(unreachable, state.Name)
+		}
+	}
+
+	report.addIssue(IssueUnreachable, unreachable, "states are unreachable from initial states")
+	report.addIssue(IssueNoPathToFinal, m.noPathToFinal(), "no final state can be reached from states")
+
+	for _, cycle := range m.cyclesWithoutExit() {
+		report.addIssue(IssueCycleWithoutExit, cycle, "cycle has no declared transition out of it")
+	}
+
+	var finals []string
+
+	for _, state := range m.States {
+		if state.isFinal() && len(state.Transitions) > 0 {
+			finals = append(finals, state.Name)
+		}
+	}
+
+	report.addIssue(IssueFinalWithTransitions, finals, "final states have outgoing transitions")
+
+	report.Paths, report.PathsTruncated = m.paths()
+
+	return report
+}
+
+func (r *CheckReport) addIssue(kind IssueKind, states []string, message string) {
+	if len(states) == 0 {
+		return
+	}
+
+	r.Issues = append(r.Issues, CheckIssue{
+		Kind:    kind,
+		States:  states,
+		Message: message,
+	})
+}
+
+// String отчет в текстовом виде
+func (r *CheckReport) String() string {
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "%s (v.%s): %d states, %d paths", r.Model, r.ETag, len(r.States), len(r.Paths))
+
+	if r.PathsTruncated {
+		b.WriteString(" (truncated)")
+	}
+
+	b.WriteString("\n")
+
+	for _, path := range r.Paths {
+		fmt.Fprintf(b, "  path: %s\n", strings.Join(path, " -> "))
+	}
+
+	for _, issue := range r.Issues {
+		fmt.Fprintf(b, "  %s: %s: %s\n", issue.Kind, issue.Message, strings.Join(issue.States, ", "))
+	}
+
+	for _, drift := range r.Drift {
+		fmt.Fprintf(b, "  %s: %s\n", drift.Kind, drift.Path)
+		b.WriteString(drift.Diff)
+	}
+
+	return b.String()
+}
+
+func (s *State) isFinal() bool {
+	return s.SuccessFinal || s.FailFinal
+}
+
+// reachable состояния, достижимые из начальных
+func (m *Model) reachable() map[*State]bool {
+	visited := make(map[*State]bool, len(m.States))
+
+	var visit func(state *State)
+
+	visit = func(state *State) {
+		if visited[state] {
+			return
+		}
+
+		visited[state] = true
+
+		for _, transition := range state.Transitions {
+			visit(transition.State)
+		}
+	}
+
+	for _, state := range m.States {
+		if state.Initial {
+			visit(state)
+		}
+	}
+
+	return visited
+}
+
+// noPathToFinal состояния, из которых нельзя попасть в конечное состояние
+func (m *Model) noPathToFinal() []string {
+	canFinish := make(map[*State]bool, len(m.States))
+
+	for _, state := range m.States {
+		if state.isFinal() {
+			canFinish[state] = true
+		}
+	}
+
+	// распространяем признак по обратным ребрам, пока он меняется
+	for changed := true; changed; {
+		changed = false
+
+		for _, state := range m.States {
+			if canFinish[state] {
+				continue
+			}
+
+			for _, transition := range state.Transitions {
+				if canFinish[transition.State] {
+					canFinish[state] = true
+					changed = true
+
+					break
+				}
+			}
+		}
+	}
+
+	var states []string
+
+	for _, state := range m.States {
+		if !canFinish[state] {
+			states = append(states, state.Name)
+		}
+	}
+
+	return states
+}
+
+// cyclesWithoutExit компоненты сильной связности (циклы), из которых нет объявленного перехода наружу
+func (m *Model) cyclesWithoutExit() [][]string {
+	var (
+		index   = make(map[*State]int, len(m.States))
+		lowLink = make(map[*State]int, len(m.States))
+		onStack = make(map[*State]bool, len(m.States))
+		stack   []*State
+		next    int
+		cycles  [][]string
+	)
+
+	var strongConnect func(state *State)
+
+	// алгоритм Тарьяна
+	strongConnect = func(state *State) {
+		index[state] = next
+		lowLink[state] = next
+		next++
+
+		stack = append(stack, state)
+		onStack[state] = true
+
+		for _, transition := range state.Transitions {
+			to := transition.State
+
+			if _, ok := index[to]; !ok {
+				strongConnect(to)
+
+				if lowLink[to] < lowLink[state] {
+					lowLink[state] = lowLink[to]
+				}
+			} else if onStack[to] && index[to] < lowLink[state] {
+				lowLink[state] = index[to]
+			}
+		}
+
+		if lowLink[state] != index[state] {
+			return
+		}
+
+		component := make(map[*State]bool)
+
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component[top] = true
+
+			if top == state {
+				break
+			}
+		}
+
+		// одиночное состояние циклом не является (переходы в себя запрещены)
+		if len(component) > 1 && !hasExit(component) {
+			cycles = append(cycles, m.names(component))
+		}
+	}
+
+	for _, state := range m.States {
+		if _, ok := index[state]; !ok {
+			strongConnect(state)
+		}
+	}
+
+	return cycles
+}
+
+// hasExit из компоненты есть объявленный переход наружу
+func hasExit(component map[*State]bool) bool {
+	for state := range component {
+		for _, transition := range state.Transitions {
+			if !transition.Fallback && !component[transition.State] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// names названия состояний множества в порядке объявления в модели
+func (m *Model) names(set map[*State]bool) []string {
+	names := make([]string, 0, len(set))
+
+	for _, state := range m.States {
+		if set[state] {
+			names = append(names, state.Name)
+		}
+	}
+
+	return names
+}
+
+// paths пути без повторов состояний от начальных состояний до конечных, не больше maxCheckPaths
+func (m *Model) paths() ([][]string, bool) {
+	var (
+		paths     = [][]string{}
+		truncated bool
+		path      []*State
+		onPath    = make(map[*State]bool, len(m.States))
+	)
+
+	var walk func(state *State)
+
+	walk = func(state *State) {
+		if truncated || onPath[state] {
+			return
+		}
+
+		path = append(path, state)
+		onPath[state] = true
+
+		defer func() {
+			path = path[:len(path)-1]
+			onPath[state] = false
+		}()
+
+		if state.isFinal() {
+			if len(paths) == maxCheckPaths {
+				truncated = true
+
+				return
+			}
+
+			names := make([]string, 0, len(path))
+
+			for _, s := range path {
+				names = append(names, s.Name)
+			}
+
+			paths = append(paths, names)
+		}
+
+		for _, transition := range state.Transitions {
+			walk(transition.State)
+		}
+	}
+
+	for _, state := range m.States {
+		if state.Initial {
+			walk(state)
+		}
+	}
+
+	return paths, truncated
+}
@@ -0,0 +1,238 @@
+package fsm_generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeYAML записывает yaml модели во временный файл name.yaml
+func writeYAML(t *testing.T, name, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name+".yaml")
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+
+	return path
+}
+
+// loadYAML разбирает модель из yaml, записанного во временный файл name.yaml
+func loadYAML(t *testing.T, name, body string) *Model {
+	t.Helper()
+
+	m, err := LoadModel(writeYAML(t, name, body))
+	require.NoError(t, err)
+
+	return m
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		issues map[IssueKind][]string
+		paths  [][]string
+	}{
+		{
+			name: "ok",
+			yaml: `
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: DONE
+  - name: DONE
+    success_final: true
+`,
+			paths: [][]string{{"CREATED", "DONE"}},
+		},
+		{
+			name: "unreachable",
+			yaml: `
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: DONE
+  - name: ORPHAN
+    disable_fallback_state: true
+    transitions:
+      - to: DONE
+  - name: DONE
+    success_final: true
+`,
+			issues: map[IssueKind][]string{IssueUnreachable: {"ORPHAN"}},
+			paths:  [][]string{{"CREATED", "DONE"}},
+		},
+		{
+			name: "no path to final",
+			yaml: `
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: LOOP_A
+      - to: DONE
+  - name: LOOP_A
+    disable_fallback_state: true
+    transitions:
+      - to: LOOP_B
+  - name: LOOP_B
+    disable_fallback_state: true
+    transitions:
+      - to: LOOP_A
+  - name: DONE
+    success_final: true
+`,
+			issues: map[IssueKind][]string{
+				IssueNoPathToFinal:    {"LOOP_A", "LOOP_B"},
+				IssueCycleWithoutExit: {"LOOP_A", "LOOP_B"},
+			},
+			paths: [][]string{{"CREATED", "DONE"}},
+		},
+		{
+			name: "cycle left only by fallback",
+			yaml: `
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: LOOP_A
+  - name: LOOP_A
+    transitions:
+      - to: LOOP_B
+  - name: LOOP_B
+    disable_fallback_state: true
+    transitions:
+      - to: LOOP_A
+`,
+			// fallback состояние конечное, но выход из цикла только через исчерпание попыток
+			issues: map[IssueKind][]string{IssueCycleWithoutExit: {"LOOP_A", "LOOP_B"}},
+			paths:  [][]string{{"CREATED", "LOOP_A", "LOOP_A_FAILED"}},
+		},
+		{
+			name: "cycle with exit",
+			yaml: `
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: CHECK
+  - name: CHECK
+    disable_fallback_state: true
+    transitions:
+      - to: WAIT
+      - to: DONE
+  - name: WAIT
+    disable_fallback_state: true
+    transitions:
+      - to: CHECK
+  - name: DONE
+    success_final: true
+`,
+			paths: [][]string{{"CREATED", "CHECK", "DONE"}},
+		},
+		{
+			name: "final with transitions",
+			yaml: `
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: ERR
+  - name: ERR
+    fail_final: true
+    transitions:
+      - to: DONE
+  - name: DONE
+    success_final: true
+`,
+			issues: map[IssueKind][]string{IssueFinalWithTransitions: {"ERR"}},
+			paths:  [][]string{{"CREATED", "ERR"}, {"CREATED", "ERR", "DONE"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := loadYAML(t, "checked", tt.yaml).Check()
+
+			issues := make(map[IssueKind][]string, len(report.Issues))
+			for _, issue := range report.Issues {
+				issues[issue.Kind] = issue.States
+			}
+
+			if tt.issues == nil {
+				tt.issues = map[IssueKind][]string{}
+			}
+
+			assert.Equal(t, tt.issues, issues)
+			assert.Equal(t, len(tt.issues) == 0, report.OK())
+			assert.Equal(t, tt.paths, report.Paths)
+			assert.False(t, report.PathsTruncated)
+		})
+	}
+}
+
+func TestCheckEdges(t *testing.T) {
+	report := loadYAML(t, "edges", `
+states:
+  - name: CREATED
+    initial: true
+    transitions:
+      - to: DONE
+  - name: DONE
+    success_final: true
+`).Check()
+
+	assert.Equal(t, []string{"CREATED", "CREATED_FAILED", "DONE"}, report.States)
+	assert.Equal(t, []CheckEdge{
+		{From: "CREATED", To: "CREATED", Kind: EdgeRetry},
+		{From: "CREATED", To: "DONE", Kind: EdgeTransition},
+		{From: "CREATED", To: "CREATED_FAILED", Kind: EdgeFallback},
+	}, report.Edges)
+}
+
+func TestCheckPathsTruncated(t *testing.T) {
+	// layers слоев по два параллельных состояния дают 2^layers путей
+	const layers = 10
+
+	b := &strings.Builder{}
+	b.WriteString("states:\n  - name: S0\n    initial: true\n    disable_fallback_state: true\n" +
+		"    transitions:\n      - to: A1\n      - to: B1\n")
+
+	for i := 1; i <= layers; i++ {
+		next := []string{fmt.Sprintf("A%d", i+1), fmt.Sprintf("B%d", i+1)}
+		if i == layers {
+			next = []string{"DONE"}
+		}
+
+		for _, name := range []string{"A", "B"} {
+			fmt.Fprintf(b, "  - name: %s%d\n    disable_fallback_state: true\n    transitions:\n", name, i)
+
+			for _, to := range next {
+				fmt.Fprintf(b, "      - to: %s\n", to)
+			}
+		}
+	}
+
+	b.WriteString("  - name: DONE\n    success_final: true\n")
+
+	report := loadYAML(t, "wide", b.String()).Check()
+
+	require.Greater(t, 1<<layers, maxCheckPaths)
+	assert.Len(t, report.Paths, maxCheckPaths)
+	assert.True(t, report.PathsTruncated)
+	assert.True(t, report.OK())
+	assert.Contains(t, report.String(), fmt.Sprintf("%d paths (truncated)", maxCheckPaths))
+}
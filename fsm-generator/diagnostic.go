@@ -0,0 +1,248 @@
+package fsm_generator
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position положение в yaml файле модели, Line и Column начинаются с 1 (0 – неизвестно)
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	s := p.File
+
+	if p.Line > 0 {
+		s += ":" + strconv.Itoa(p.Line)
+
+		if p.Column > 0 {
+			s += ":" + strconv.Itoa(p.Column)
+		}
+	}
+
+	return s
+}
+
+// Diagnostic ошибка в yaml модели с указанием положения
+type Diagnostic struct {
+	Pos Position
+	Msg string
+}
+
+func (d *Diagnostic) Error() string {
+	return d.Pos.String() + ": " + d.Msg
+}
+
+// Diagnostics все найденные в модели ошибки, по одной на строку в формате file:line:col: msg
+type Diagnostics []*Diagnostic
+
+func (ds Diagnostics) Error() string {
+	lines := make([]string, 0, len(ds))
+
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// err nil, если ошибок нет
+func (ds Diagnostics) err() error {
+	if len(ds) == 0 {
+		return nil
+	}
+
+	return ds
+}
+
+// add добавляет ошибку в положении pos
+func (ds *Diagnostics) add(pos Position, format string, args ...interface{}) {
+	*ds = append(*ds, &Diagnostic{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// source положение элемента модели и его полей в yaml файле
+type source struct {
+	pos    Position
+	fields map[string]Position
+}
+
+func newSource(file string, node *yaml.Node) source {
+	s := source{
+		pos:    Position{File: file, Line: node.Line, Column: node.Column},
+		fields: make(map[string]Position),
+	}
+
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			s.fields[key.Value] = Position{File: file, Line: key.Line, Column: key.Column}
+		}
+	}
+
+	return s
+}
+
+// at положение поля, либо самого элемента, если поле не задано в yaml
+func (s source) at(field string) Position {
+	if pos, ok := s.fields[field]; ok {
+		return pos
+	}
+
+	return s.pos
+}
+
+// stateAt положение поля состояния, для значений из default_config – положение в default_config
+func (m *Model) stateAt(state *State, name string) Position {
+	if pos, ok := state.src.fields[name]; ok {
+		return pos
+	}
+
+	if pos, ok := m.DefaultConfig.src.fields[name]; ok {
+		return pos
+	}
+
+	return state.src.pos
+}
+
+// field значение поля в yaml mapping, либо nil
+func field(node *yaml.Node, name string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == name {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// items элементы yaml последовательности
+func items(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	return node.Content
+}
+
+// locate запоминает положения элементов модели, разобранной из документа root
+func (m *Model) locate(file string, root *yaml.Node) {
+	m.src = newSource(file, root)
+
+	if node := field(root, "default_config"); node != nil && m.DefaultConfig != nil {
+		m.DefaultConfig.src = newSource(file, node)
+	}
+
+	states := items(field(root, "states"))
+
+	for i, state := range m.States {
+		if i >= len(states) {
+			break
+		}
+
+		state.src = newSource(file, states[i])
+
+		transitions := items(field(states[i], "transitions"))
+
+		for j, transition := range state.Transitions {
+			if j < len(transitions) {
+				transition.src = newSource(file, transitions[j])
+			}
+		}
+	}
+
+	context := items(field(root, "context"))
+
+	for i, f := range m.Context {
+		if i < len(context) {
+			f.src = newSource(file, context[i])
+		}
+	}
+}
+
+// checkFields проверяет, что все ключи yaml известны типу t: аналог yaml KnownFields, но с положением каждого ключа
+func checkFields(file string, node *yaml.Node, t reflect.Type) Diagnostics {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var ds Diagnostics
+
+	switch {
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for _, item := range node.Content {
+			ds = append(ds, checkFields(file, item, t.Elem())...)
+		}
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := make(map[string]reflect.Type, t.NumField())
+
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+
+			ft, ok := fields[key.Value]
+			if !ok {
+				ds.add(Position{File: file, Line: key.Line, Column: key.Column}, "unknown field %q", key.Value)
+				continue
+			}
+
+			ds = append(ds, checkFields(file, node.Content[i+1], ft)...)
+		}
+	}
+
+	return ds
+}
+
+// yamlLineErr строка ошибки yaml: "line 12: ..." или "yaml: line 12: ..."
+var yamlLineErr = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlDiagnostics приводит ошибки разбора yaml к виду file:line: msg
+func yamlDiagnostics(file string, err error) Diagnostics {
+	var messages []string
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+
+	var ds Diagnostics
+
+	for _, msg := range messages {
+		pos := Position{File: file}
+
+		if match := yamlLineErr.FindStringSubmatch(msg); match != nil {
+			pos.Line, _ = strconv.Atoi(match[1])
+			msg = match[2]
+		}
+
+		ds.add(pos, "%s", msg)
+	}
+
+	return ds
+}
+
+// ModelSchema JSON Schema yaml модели для автодополнения и проверки в редакторах
+//
+//go:embed model.schema.json
+var ModelSchema []byte
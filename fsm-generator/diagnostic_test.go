@@ -0,0 +1,124 @@
+package fsm_generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModelDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		// errors ожидаемые строки ошибок без пути к файлу
+		errors []string
+	}{
+		{
+			name: "unknown field",
+			yaml: `states:
+  - name: CREATED
+    initial: true
+    retries: 3
+`,
+			errors: []string{`:4:5: unknown field "retries"`},
+		},
+		{
+			name: "unknown fields are all reported",
+			yaml: `titel: "model"
+states:
+  - name: CREATED
+    transitions:
+      - too: DONE
+`,
+			errors: []string{`:1:1: unknown field "titel"`, `:5:9: unknown field "too"`},
+		},
+		{
+			name: "bad transition target",
+			yaml: `states:
+  - name: CREATED
+    initial: true
+    transitions:
+      - to: DONE
+      - to: MISSING
+  - name: DONE
+    success_final: true
+`,
+			errors: []string{`:6:9: no state MISSING found for transition from state CREATED`},
+		},
+		{
+			name: "transition in itself",
+			yaml: `states:
+  - name: CREATED
+    initial: true
+    transitions:
+      - to: CREATED
+`,
+			errors: []string{`:5:9: state CREATED can transit in itself`},
+		},
+		{
+			name: "type error",
+			yaml: `default_config:
+  max_retry_count: many
+`,
+			errors: []string{`:2: cannot unmarshal !!str ` + "`many`" + ` into int`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeYAML(t, "broken", tt.yaml)
+
+			_, err := LoadModel(path)
+			require.Error(t, err)
+
+			var ds Diagnostics
+			require.True(t, errors.As(err, &ds), err)
+
+			lines := strings.Split(err.Error(), "\n")
+			require.Len(t, lines, len(tt.errors), err)
+
+			for i, line := range lines {
+				assert.Equal(t, path+tt.errors[i], line)
+			}
+		})
+	}
+}
+
+func TestValidateModelDiagnostics(t *testing.T) {
+	path := writeYAML(t, "invalid", `default_config:
+  min_retry_delay: 10ms
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: DONE
+  - name: DONE
+    success_final: true
+  - name: ORPHAN
+    success_final: true
+`)
+
+	m, err := LoadModel(path)
+	require.NoError(t, err)
+
+	err = m.ValidateModel()
+	require.Error(t, err)
+
+	// значение из default_config указывает на default_config, а не на состояние
+	assert.Equal(t, strings.Join([]string{
+		path + ":2:3: CREATED: min retry delay should be times of 1 second (or be equal zero)",
+		path + ":2:3: DONE: min retry delay should be times of 1 second (or be equal zero)",
+		path + ":2:3: ORPHAN: min retry delay should be times of 1 second (or be equal zero)",
+		path + ":11:5: ORPHAN: state is unreachable from initial states",
+	}, "\n"), err.Error())
+}
+
+func TestPosition(t *testing.T) {
+	assert.Equal(t, "first.yaml", Position{File: "first.yaml"}.String())
+	assert.Equal(t, "first.yaml:3", Position{File: "first.yaml", Line: 3}.String())
+	assert.Equal(t, "first.yaml:3:7", Position{File: "first.yaml", Line: 3, Column: 7}.String())
+}
@@ -0,0 +1,42 @@
+package fsm_generator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RenderDocs генерирует в памяти markdown документацию модели
+func (t *TemplateBuilder) RenderDocs(model *Model) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	err := t.Templates.ExecuteTemplate(buf, "model.md.tpl", &TemplateModel{Model: model})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GenerateDocs создает документацию модели dir/<model>.md
+func (t *TemplateBuilder) GenerateDocs(model *Model, dir string) error {
+	content, err := t.RenderDocs(model)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0750)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Clean(filepath.Join(dir, model.Name+".md")), content, 0600)
+}
+
+var markdownCellReplacer = strings.NewReplacer("|", `\|`, "\r", "", "\n", "<br>")
+
+// markdownCell экранирует текст для ячейки markdown таблицы
+func markdownCell(s string) string {
+	return strings.TrimSpace(markdownCellReplacer.Replace(s))
+}
@@ -0,0 +1,105 @@
+package fsm_generator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DriftKind вид расхождения сгенерированных файлов с моделью
+type DriftKind string
+
+const (
+	// DriftStale сгенерированный файл (DO NOT EDIT) отличается от результата генерации по модели
+	DriftStale DriftKind = "stale"
+	// DriftMissing файл модели не сгенерирован
+	DriftMissing DriftKind = "missing"
+	// DriftOrphan файл пакета модели не соответствует модели (например, обработчик удаленного состояния)
+	DriftOrphan DriftKind = "orphan"
+)
+
+type Drift struct {
+	Kind DriftKind `json:"kind"`
+	Path string    `json:"path"`
+	// Diff unified diff файла на диске относительно результата генерации (для DriftStale)
+	Diff string `json:"diff,omitempty"`
+}
+
+// DiffModel генерирует файлы модели и ее документацию docsDir/<model>.md (если docsDir не пуст) в памяти
+// и сравнивает их с файлами на диске, редактируемые файлы (сервис, обработчики) проверяются только на наличие
+func (t *TemplateBuilder) DiffModel(model *Model, outputDir string, docsDir string) ([]*Drift, error) {
+	files, err := t.RenderModel(model, outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if docsDir != "" {
+		docs, err := t.RenderDocs(model)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, &GeneratedFile{Path: filepath.Join(docsDir, model.Name+".md"), Content: docs})
+	}
+
+	var drifts []*Drift
+
+	generated := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		generated[filepath.Base(file.Path)] = true
+
+		onDisk, err := os.ReadFile(filepath.Clean(file.Path))
+		if os.IsNotExist(err) {
+			drifts = append(drifts, &Drift{Kind: DriftMissing, Path: file.Path})
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if file.Editable || bytes.Equal(onDisk, file.Content) {
+			continue
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(onDisk)),
+			B:        difflib.SplitLines(string(file.Content)),
+			FromFile: file.Path,
+			ToFile:   file.Path + " (generated)",
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		drifts = append(drifts, &Drift{Kind: DriftStale, Path: file.Path, Diff: diff})
+	}
+
+	// генератор ничего не удаляет: файлы удаленных состояний остаются в пакете
+	entries, err := os.ReadDir(ModelDir(model, outputDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".fsm.go") || generated[name] {
+			continue
+		}
+
+		drifts = append(drifts, &Drift{Kind: DriftOrphan, Path: filepath.Join(ModelDir(model, outputDir), name)})
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Path < drifts[j].Path
+	})
+
+	return drifts, nil
+}
@@ -0,0 +1,132 @@
+package fsm_generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const driftModelYAML = `
+states:
+  - name: CREATED
+    initial: true
+    disable_fallback_state: true
+    transitions:
+      - to: DONE
+  - name: DONE
+    success_final: true
+`
+
+func TestDiffModel(t *testing.T) {
+	tb, err := NewTemplateBuilder()
+	require.NoError(t, err)
+
+	model := loadYAML(t, "drifted", driftModelYAML)
+	model.Package = "drifted"
+
+	outputDir := t.TempDir()
+	dir := ModelDir(model, outputDir)
+
+	require.NoError(t, tb.GenerateModel(model, outputDir))
+
+	drifts, err := tb.DiffModel(model, outputDir, "")
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// редактируемые файлы сравниваются только на наличие
+	handler := filepath.Join(dir, "created.handler.fsm.go")
+	require.NoError(t, os.WriteFile(handler, []byte("package drifted\n\n// edited\n"), 0o600))
+
+	// сгенерированный файл изменен вручную
+	stale := filepath.Join(dir, "drifted_model.fsm.go")
+	content, err := os.ReadFile(stale)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(stale, append(content, []byte("\n// edited\n")...), 0o600))
+
+	// файл состояния удален
+	missing := filepath.Join(dir, "done.fsm.go")
+	require.NoError(t, os.Remove(missing))
+
+	// файл удаленного из модели состояния
+	orphan := filepath.Join(dir, "removed.fsm.go")
+	require.NoError(t, os.WriteFile(orphan, []byte("package drifted\n"), 0o600))
+
+	// не сгенерированные генератором файлы не учитываются
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "helpers.go"), []byte("package drifted\n"), 0o600))
+
+	drifts, err = tb.DiffModel(model, outputDir, "")
+	require.NoError(t, err)
+	require.Len(t, drifts, 3)
+
+	assert.Equal(t, DriftMissing, drifts[0].Kind)
+	assert.Equal(t, missing, drifts[0].Path)
+
+	assert.Equal(t, DriftStale, drifts[1].Kind)
+	assert.Equal(t, stale, drifts[1].Path)
+	assert.Contains(t, drifts[1].Diff, "-// edited")
+
+	assert.Equal(t, DriftOrphan, drifts[2].Kind)
+	assert.Equal(t, orphan, drifts[2].Path)
+}
+
+func TestDiffModelNotGenerated(t *testing.T) {
+	tb, err := NewTemplateBuilder()
+	require.NoError(t, err)
+
+	model := loadYAML(t, "drifted", driftModelYAML)
+	model.Package = "drifted"
+
+	drifts, err := tb.DiffModel(model, t.TempDir(), "")
+	require.NoError(t, err)
+	require.NotEmpty(t, drifts)
+
+	for _, drift := range drifts {
+		assert.Equal(t, DriftMissing, drift.Kind, drift.Path)
+	}
+}
+
+func TestDiffModelDocs(t *testing.T) {
+	tb, err := NewTemplateBuilder()
+	require.NoError(t, err)
+
+	model := loadYAML(t, "drifted", driftModelYAML)
+	model.Package = "drifted"
+
+	outputDir := t.TempDir()
+	docsDir := t.TempDir()
+	docs := filepath.Join(docsDir, "drifted.md")
+
+	require.NoError(t, tb.GenerateModel(model, outputDir))
+
+	// документация не создана
+	drifts, err := tb.DiffModel(model, outputDir, docsDir)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, &Drift{Kind: DriftMissing, Path: docs}, drifts[0])
+
+	require.NoError(t, tb.GenerateDocs(model, docsDir))
+
+	drifts, err = tb.DiffModel(model, outputDir, docsDir)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	// документация (DO NOT EDIT) изменена вручную
+	content, err := os.ReadFile(docs)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(docs, append(content, []byte("edited\n")...), 0o600))
+
+	drifts, err = tb.DiffModel(model, outputDir, docsDir)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, DriftStale, drifts[0].Kind)
+	assert.Equal(t, docs, drifts[0].Path)
+	assert.Contains(t, drifts[0].Diff, "-edited")
+
+	// без docsDir документация не проверяется
+	drifts, err = tb.DiffModel(model, outputDir, "")
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
@@ -4,41 +4,138 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+
+	"fsm-framework/fsm-engine/migration"
 )
 
 const (
-	modelsDir    = "internal/fsm/models"
-	previewsDir  = "internal/fsm/previews"
-	outputFsmDir = "internal/app/fsm"
-
 	templatesDir = "templates/*.fsm.go.tpl"
+	// docsTemplatesDir шаблоны документации моделей
+	docsTemplatesDir = "templates/*.md.tpl"
+
+	// packageModelPlaceholder заменяется в Config.Package названием модели в snake_case
+	packageModelPlaceholder = "{model}"
 )
 
-func Generate() error {
+// Config пути и параметры генерации, пути задаются относительно текущей директории либо абсолютные
+type Config struct {
+	// ModelsDir директория yaml моделей
+	ModelsDir string
+	// MigrationsDir директория миграций транзакций моделей
+	MigrationsDir string
+	// PreviewsDir директория превью графов моделей
+	PreviewsDir string
+	// PreviewFormats форматы превью (пусто – DefaultPreviewFormats)
+	PreviewFormats []PreviewFormat
+	// DocsDir директория markdown документации моделей (пусто – документация не создается)
+	DocsDir string
+	// OutputDir директория, в которой создаются пакеты моделей
+	OutputDir string
+	// Models названия обрабатываемых моделей (пусто – все модели ModelsDir)
+	Models []string
+	// Package название go пакета модели, {model} заменяется названием модели в snake_case
+	Package string
+	// PackageDir директория пакета модели в OutputDir, {model} заменяется названием модели (пусто – Package)
+	PackageDir string
+	// Framework путь go модуля fsm-framework для импортов сгенерированного кода (пусто – определяется по go.mod,
+	// найденному от OutputDir, см. ResolveFramework)
+	Framework string
+}
+
+// DefaultConfig структура проекта по умолчанию (см. docs/example-project)
+func DefaultConfig() Config {
+	return Config{
+		ModelsDir:     "internal/fsm/models",
+		MigrationsDir: "internal/fsm/migrations",
+		PreviewsDir:   "internal/fsm/previews",
+		DocsDir:       "internal/fsm/docs",
+		OutputDir:     "internal/app/fsm",
+		Package:       packageModelPlaceholder,
+	}
+}
+
+// Generate проверяет модели, создает их превью и документацию и генерирует файлы
+func Generate(cfg Config) error {
 	tb, err := NewTemplateBuilder()
 	if err != nil {
 		return err
 	}
 
-	models, err := loadModels(modelsDir)
+	models, err := Validate(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = resolvePackages(cfg, models)
 	if err != nil {
 		return err
 	}
 
 	for _, model := range models {
-		err = model.ValidateModel()
+		model.PrintStates()
+
+		err = model.MakePreview(cfg.PreviewsDir, cfg.PreviewFormats...)
 		if err != nil {
-			return fmt.Errorf("%s model validation err: %w", model.Name, err)
+			return err
 		}
 
-		model.PrintStates()
+		if cfg.DocsDir != "" {
+			err = tb.GenerateDocs(model, cfg.DocsDir)
+			if err != nil {
+				return err
+			}
+		}
 
-		err = model.MakePreview()
+		err = tb.GenerateModel(model, cfg.OutputDir)
 		if err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+// Validate загружает и проверяет модели и их миграции, ничего не записывая
+func Validate(cfg Config) ([]*Model, error) {
+	models, err := LoadModels(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range models {
+		err = model.ValidateModel()
+		if err != nil {
+			return nil, fmt.Errorf("%s model validation err: %w", model.Name, err)
+		}
 
-		err = tb.GenerateModel(model)
+		model.Migration, err = loadMigration(cfg.MigrationsDir, model.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%s model migration err: %w", model.Name, err)
+		}
+
+		if model.Migration != nil {
+			err = model.ValidateMigration()
+			if err != nil {
+				return nil, fmt.Errorf("%s model migration validation err: %w", model.Name, err)
+			}
+		}
+	}
+
+	return models, nil
+}
+
+// Preview создает только превью графов моделей
+func Preview(cfg Config) error {
+	models, err := LoadModels(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, model := range models {
+		err = model.MakePreview(cfg.PreviewsDir, cfg.PreviewFormats...)
 		if err != nil {
 			return err
 		}
@@ -47,12 +144,74 @@ func Generate() error {
 	return nil
 }
 
-func loadModels(modelsPath string) ([]*Model, error) {
+// CheckModels проверяет модели, исследует их графы (Model.Check)
+// и сравнивает сгенерированный код и документацию с результатом генерации (TemplateBuilder.DiffModel)
+func CheckModels(cfg Config) ([]*CheckReport, error) {
+	tb, err := NewTemplateBuilder()
+	if err != nil {
+		return nil, err
+	}
+
+	models, err := Validate(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resolvePackages(cfg, models)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*CheckReport, 0, len(models))
+
+	for _, model := range models {
+		report := model.Check()
+
+		report.Drift, err = tb.DiffModel(model, cfg.OutputDir, cfg.DocsDir)
+		if err != nil {
+			return nil, fmt.Errorf("%s model drift err: %w", model.Name, err)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// LoadModels разбирает yaml модели из ModelsDir с учетом фильтра Models
+func LoadModels(cfg Config) ([]*Model, error) {
+	models, err := loadModels(cfg.ModelsDir, cfg.Models)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range models {
+		model.Package = strings.ReplaceAll(cfg.Package, packageModelPlaceholder, strcase.ToSnake(model.Name))
+		if model.Package == "" {
+			model.Package = strcase.ToSnake(model.Name)
+		}
+
+		model.Dir = strings.ReplaceAll(cfg.PackageDir, packageModelPlaceholder, strcase.ToSnake(model.Name))
+		if model.Dir == "" {
+			model.Dir = model.Package
+		}
+	}
+
+	return models, nil
+}
+
+func loadModels(modelsPath string, names []string) ([]*Model, error) {
 	dirEntities, err := os.ReadDir(modelsPath)
 	if err != nil {
 		return nil, err
 	}
 
+	filter := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		filter[name] = true
+	}
+
 	models := make([]*Model, 0, len(dirEntities))
 
 	for _, entity := range dirEntities {
@@ -69,20 +228,64 @@ func loadModels(modelsPath string) ([]*Model, error) {
 			continue
 		}
 
-		file, err := os.Open(filepath.Clean(filepath.Join(modelsPath, info.Name())))
-		if err != nil {
-			return nil, err
+		if len(filter) > 0 && !filter[strings.TrimSuffix(info.Name(), ".yaml")] {
+			continue
 		}
 
-		model, err := ParseModel(file)
+		model, err := LoadModel(filepath.Join(modelsPath, info.Name()))
 		if err != nil {
 			return nil, err
 		}
 
-		model.Name = info.Name()[:len(info.Name())-len(".yaml")]
-
 		models = append(models, model)
 	}
 
+	for name := range filter {
+		found := false
+
+		for _, model := range models {
+			found = found || model.Name == name
+		}
+
+		if !found {
+			return nil, fmt.Errorf("model %s not found in %s", name, modelsPath)
+		}
+	}
+
 	return models, nil
 }
+
+// LoadModel разбирает yaml модели, название модели – название файла
+func LoadModel(path string) (*Model, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	model, err := ParseModel(file)
+	if err != nil {
+		return nil, err
+	}
+
+	model.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return model, nil
+}
+
+// loadMigration вычитывает миграцию модели, если она задана
+func loadMigration(migrationsPath string, modelName string) (*migration.Spec, error) {
+	file, err := os.Open(filepath.Clean(filepath.Join(migrationsPath, modelName+".yaml")))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return migration.Parse(file)
+}
@@ -0,0 +1,194 @@
+package fsm_generator
+
+import (
+	"bufio"
+	"fmt"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// defaultFramework путь модуля фреймворка, если go.mod проекта не найден и генератор собран без информации о модуле
+const defaultFramework = "fsm-framework"
+
+// GoMod go.mod проекта, в котором генерируются пакеты моделей
+type GoMod struct {
+	// Path путь до go.mod
+	Path string
+	// Module путь модуля проекта
+	Module string
+	// Require пути модулей зависимостей
+	Require []string
+}
+
+// FindGoMod ищет go.mod в dir и родительских директориях, nil – go.mod не найден
+func FindGoMod(dir string) (*GoMod, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p := filepath.Join(dir, "go.mod")
+
+		_, err = os.Stat(p)
+		if err == nil {
+			return parseGoMod(p)
+		}
+
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+
+		dir = parent
+	}
+}
+
+// parseGoMod разбирает директивы module и require
+func parseGoMod(p string) (*GoMod, error) {
+	file, err := os.Open(filepath.Clean(p))
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	gomod := &GoMod{Path: p}
+	inRequire := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+
+		switch {
+		case len(fields) == 0:
+		case inRequire:
+			if fields[0] == ")" {
+				inRequire = false
+				continue
+			}
+
+			gomod.Require = append(gomod.Require, strings.Trim(fields[0], `"`))
+		case fields[0] == "module" && len(fields) > 1:
+			gomod.Module = strings.Trim(fields[1], `"`)
+		case fields[0] == "require" && len(fields) > 1:
+			if fields[1] == "(" {
+				inRequire = true
+				continue
+			}
+
+			gomod.Require = append(gomod.Require, strings.Trim(fields[1], `"`))
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if gomod.Module == "" {
+		return nil, fmt.Errorf("%s: module directive not found", p)
+	}
+
+	return gomod, nil
+}
+
+// ResolveFramework путь модуля фреймворка для импортов сгенерированного кода: модуль, из которого собран генератор,
+// если проект от него зависит, иначе модуль fsm-framework из go.mod проекта (сам проект или его зависимость)
+func ResolveFramework(gomod *GoMod) (string, error) {
+	own := generatorModule()
+
+	if gomod == nil {
+		if own != "" {
+			return own, nil
+		}
+
+		return defaultFramework, nil
+	}
+
+	modules := append([]string{gomod.Module}, gomod.Require...)
+
+	for _, module := range modules {
+		if module == own {
+			return own, nil
+		}
+	}
+
+	for _, module := range modules {
+		if path.Base(strings.TrimSuffix(module, ".git")) == defaultFramework {
+			return module, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: %s module not found, set framework import path with -framework flag",
+		gomod.Path, defaultFramework)
+}
+
+// generatorModule путь модуля, из которого собран генератор (go install, go run), либо пустая строка
+func generatorModule() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "command-line-arguments" {
+		return ""
+	}
+
+	return info.Main.Path
+}
+
+// resolvePackages проверяет названия пакетов моделей и определяет импорты сгенерированного кода:
+// путь модуля фреймворка (Config.Framework либо по go.mod проекта) и путь импорта пакета модели
+func resolvePackages(cfg Config, models []*Model) error {
+	for _, model := range models {
+		if !token.IsIdentifier(model.Package) {
+			return fmt.Errorf("%s model package name %q is not a valid go identifier, use -package flag",
+				model.Name, model.Package)
+		}
+	}
+
+	gomod, err := FindGoMod(cfg.OutputDir)
+	if err != nil {
+		return err
+	}
+
+	framework := cfg.Framework
+	if framework == "" {
+		framework, err = ResolveFramework(gomod)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, model := range models {
+		model.Framework = strings.TrimSuffix(framework, "/")
+
+		if gomod == nil {
+			continue
+		}
+
+		dir, err := filepath.Abs(ModelDir(model, cfg.OutputDir))
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(filepath.Dir(gomod.Path), dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		model.ImportPath = path.Join(gomod.Module, filepath.ToSlash(rel))
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package fsm_generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeGoMod(t *testing.T, dir, body string) string {
+	t.Helper()
+
+	p := filepath.Join(dir, "go.mod")
+	require.NoError(t, os.WriteFile(p, []byte(body), 0o600))
+
+	return p
+}
+
+func TestParseGoMod(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		module  string
+		require []string
+	}{
+		{
+			name: "require block",
+			body: `module example.com/service // сервис
+
+go 1.17
+
+require (
+	github.com/google/uuid v1.3.0
+	gitlab.example.com/platform/fsm-framework.git v0.4.0 // indirect
+
+	"github.com/stretchr/testify" v1.8.0
+)
+`,
+			module: "example.com/service",
+			require: []string{
+				"github.com/google/uuid",
+				"gitlab.example.com/platform/fsm-framework.git",
+				"github.com/stretchr/testify",
+			},
+		},
+		{
+			name: "single line require",
+			body: `module "example.com/service"
+
+require github.com/google/uuid v1.3.0
+require gitlab.example.com/platform/fsm-framework v0.4.0
+// require github.com/commented/out v1.0.0
+`,
+			module:  "example.com/service",
+			require: []string{"github.com/google/uuid", "gitlab.example.com/platform/fsm-framework"},
+		},
+		{
+			name:   "no require",
+			body:   "module example.com/service\n\ngo 1.17\n",
+			module: "example.com/service",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := writeGoMod(t, t.TempDir(), tt.body)
+
+			gomod, err := parseGoMod(p)
+			require.NoError(t, err)
+
+			assert.Equal(t, &GoMod{Path: p, Module: tt.module, Require: tt.require}, gomod)
+		})
+	}
+}
+
+func TestParseGoModNoModule(t *testing.T) {
+	p := writeGoMod(t, t.TempDir(), "go 1.17\n\nrequire github.com/google/uuid v1.3.0\n")
+
+	_, err := parseGoMod(p)
+	assert.EqualError(t, err, p+": module directive not found")
+}
+
+func TestFindGoMod(t *testing.T) {
+	root := t.TempDir()
+	p := writeGoMod(t, root, "module example.com/service\n")
+
+	// go.mod ищется в родительских директориях
+	dir := filepath.Join(root, "internal", "fsm")
+	require.NoError(t, os.MkdirAll(dir, 0o750))
+
+	gomod, err := FindGoMod(dir)
+	require.NoError(t, err)
+	require.NotNil(t, gomod)
+	assert.Equal(t, p, gomod.Path)
+	assert.Equal(t, "example.com/service", gomod.Module)
+}
+
+func TestResolveFramework(t *testing.T) {
+	own := generatorModule()
+
+	fallback := own
+	if fallback == "" {
+		fallback = defaultFramework
+	}
+
+	tests := []struct {
+		name  string
+		gomod *GoMod
+		want  string
+	}{
+		{
+			name: "no go.mod",
+			want: fallback,
+		},
+		{
+			name:  "framework itself",
+			gomod: &GoMod{Module: "fsm-framework"},
+			want:  "fsm-framework",
+		},
+		{
+			name: "framework dependency",
+			gomod: &GoMod{
+				Module:  "example.com/service",
+				Require: []string{"github.com/google/uuid", "gitlab.example.com/platform/fsm-framework"},
+			},
+			want: "gitlab.example.com/platform/fsm-framework",
+		},
+		{
+			name: "framework dependency with .git suffix",
+			gomod: &GoMod{
+				Module:  "example.com/service",
+				Require: []string{"gitlab.example.com/platform/fsm-framework.git"},
+			},
+			want: "gitlab.example.com/platform/fsm-framework.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			framework, err := ResolveFramework(tt.gomod)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, framework)
+		})
+	}
+}
+
+func TestResolveFrameworkNotFound(t *testing.T) {
+	gomod := &GoMod{
+		Path:    "/service/go.mod",
+		Module:  "example.com/service",
+		Require: []string{"github.com/google/uuid", "example.com/fsm-framework-fork"},
+	}
+
+	_, err := ResolveFramework(gomod)
+	assert.EqualError(t, err,
+		"/service/go.mod: fsm-framework module not found, set framework import path with -framework flag")
+}
@@ -5,7 +5,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/goccy/go-graphviz/cgraph"
+	"fsm-framework/fsm-engine/migration"
 )
 
 // ModelDefaultConfig настройки
@@ -16,6 +16,65 @@ type ModelDefaultConfig struct {
 	MinRetryDelay time.Duration `yaml:"min_retry_delay"`
 	// CancellationTTL время после которого неизмененная в текущем состоянии транзакция считается отмененной
 	CancellationTTL time.Duration `yaml:"cancellation_ttl"`
+	// Backoff стратегия задержки перед повторами (может быть nil)
+	Backoff *Backoff `yaml:"backoff,omitempty"`
+
+	// src положение настроек в yaml файле модели
+	src source
+}
+
+// backoffStrategies поддерживаемые стратегии задержки и соответствующие им константы fsm-engine/model
+var backoffStrategies = map[string]string{
+	"constant":    "BackoffConstant",
+	"linear":      "BackoffLinear",
+	"exponential": "BackoffExponential",
+}
+
+// Backoff стратегия задержки перед повторной обработкой события
+type Backoff struct {
+	// Strategy constant, linear или exponential
+	Strategy string `yaml:"strategy"`
+	// Base базовая задержка (по умолчанию min_retry_delay состояния)
+	Base time.Duration `yaml:"base,omitempty"`
+	// Max ограничение задержки сверху (0 – без ограничения)
+	Max time.Duration `yaml:"max,omitempty"`
+	// Jitter случайная задержка в интервале [0, delay) (full jitter)
+	Jitter bool `yaml:"jitter,omitempty"`
+}
+
+// StrategyConst константа стратегии в fsm-engine/model
+func (b *Backoff) StrategyConst() string {
+	return backoffStrategies[b.Strategy]
+}
+
+// contextFieldTypes поддерживаемые типы полей контекстных данных и соответствующие им типы go
+var contextFieldTypes = map[string]string{
+	"string":   "string",
+	"int":      "int",
+	"int64":    "int64",
+	"float":    "float64",
+	"bool":     "bool",
+	"time":     "time.Time",
+	"duration": "time.Duration",
+	"uuid":     "uuid.UUID",
+}
+
+// ContextField поле контекстных данных модели, передаваемых между состояниями
+type ContextField struct {
+	// Name название поля в snake_case
+	Name string `yaml:"name"`
+	// Type тип поля (string, int, int64, float, bool, time, duration, uuid)
+	Type string `yaml:"type"`
+	// Description описание поля
+	Description string `yaml:"description,omitempty"`
+
+	// src положение поля в yaml файле модели
+	src source
+}
+
+// GoType тип поля в сгенерированной структуре
+func (f *ContextField) GoType() string {
+	return contextFieldTypes[f.Type]
 }
 
 // Transition разрешенный переход из одного состояния в другое
@@ -25,8 +84,14 @@ type Transition struct {
 	// Condition описание условий перехода
 	Condition string `yaml:"condition"`
 
+	// Fallback переход в fallback failed state при исчерпании попыток, созданный ParseModel
+	Fallback bool `yaml:"-"`
+
 	// State структура, найденная по названию состояния
 	State *State `yaml:"-"`
+
+	// src положение перехода в yaml файле модели
+	src source
 }
 
 type State struct {
@@ -48,11 +113,38 @@ type State struct {
 	MinRetryDelay time.Duration `yaml:"min_retry_delay"`
 	// CancellationTTL время после которого неизмененная в текущем состоянии транзакция считается отмененной
 	CancellationTTL time.Duration `yaml:"cancellation_ttl"`
+	// Backoff стратегия задержки перед повторами (по умолчанию из default_config)
+	Backoff *Backoff `yaml:"backoff,omitempty"`
+	// Compensation флаг, включающий компенсирующий обработчик состояния (saga),
+	// исполняемый при попадании транзакции в конечное неудачное состояние
+	Compensation bool `yaml:"compensation,omitempty"`
+	// RateLimit ограничение частоты обработки событий состояния для всех реплик сервиса (может быть nil)
+	RateLimit *RateLimit `yaml:"rate_limit,omitempty"`
+	// CircuitBreaker приостановка обработки очереди состояния при серии неудач обработчика (может быть nil)
+	CircuitBreaker *CircuitBreaker `yaml:"circuit_breaker,omitempty"`
 	// Transitions список разрешенных переходов из текущего состояния
 	Transitions []*Transition `yaml:"transitions"`
 
-	// GraphNode структура, создаваемая в рамках отрисовки графа
-	GraphNode *cgraph.Node `yaml:"-"`
+	// src положение состояния в yaml файле модели (fallback состояния – положение исходного)
+	src source
+}
+
+// RateLimit ограничение частоты обработки событий состояния,
+// события сверх ограничения откладываются, а не повторяются
+type RateLimit struct {
+	// Rate количество событий в секунду (может быть дробным: 0.5 – одно событие в 2 секунды)
+	Rate float64 `yaml:"rate"`
+	// Burst количество событий, обрабатываемых единовременно без задержки (по умолчанию 1)
+	Burst int `yaml:"burst,omitempty"`
+}
+
+// CircuitBreaker приостанавливает обработку очереди состояния после серии неудач обработчика
+// и возобновляет ее после успешного пробного события
+type CircuitBreaker struct {
+	// FailureThreshold количество неудач подряд (паника или повтор), после которого обработка приостанавливается
+	FailureThreshold int `yaml:"failure_threshold"`
+	// OpenTimeout время приостановки до пробного события
+	OpenTimeout time.Duration `yaml:"open_timeout"`
 }
 
 // Model fsm-модель, содержащая описания состояний и переходы между ними
@@ -61,12 +153,38 @@ type Model struct {
 	Name string `yaml:"-"`
 	// ETag hash в hex, по нему можно понять изменилась ли модель
 	ETag string `yaml:"-"`
+	// Package название go пакета сгенерированной модели (см. Config.Package)
+	Package string `yaml:"-"`
+	// Dir директория пакета модели в Config.OutputDir (см. Config.PackageDir)
+	Dir string `yaml:"-"`
+	// ImportPath путь импорта пакета модели, если он находится в модуле проекта (может быть пустым)
+	ImportPath string `yaml:"-"`
+	// Framework путь go модуля fsm-framework для импортов сгенерированного кода (см. Config.Framework)
+	Framework string `yaml:"-"`
 	// Title кириллическое название модели
 	Title string `yaml:"title"`
 	// DefaultConfig настройки переходов между состояниями по умолчанию
 	DefaultConfig *ModelDefaultConfig `yaml:"default_config"`
 	// States список состояний модели
 	States []*State `yaml:"states"`
+	// Context декларация контекстных данных модели, передаваемых между состояниями
+	Context []*ContextField `yaml:"context,omitempty"`
+
+	// Migration миграция транзакций из удаленных или переименованных состояний (может быть nil)
+	Migration *migration.Spec `yaml:"-"`
+
+	// src положение корня модели в yaml файле
+	src source
+}
+
+// Import путь импорта пакета fsm-engine/<pkg> фреймворка
+func (m *Model) Import(pkg string) string {
+	framework := m.Framework
+	if framework == "" {
+		framework = defaultFramework
+	}
+
+	return framework + "/fsm-engine/" + pkg
 }
 
 func (m *Model) Prefix() string {
@@ -84,3 +202,58 @@ func (m *Model) PrintStates() {
 
 	fmt.Printf("| ---------------------------------------\n\n")
 }
+
+// ContextUsesTime поля контекстных данных используют пакет time
+func (m *Model) ContextUsesTime() bool {
+	for _, field := range m.Context {
+		if field.Type == "time" || field.Type == "duration" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContextUsesUUID поля контекстных данных используют пакет uuid
+func (m *Model) ContextUsesUUID() bool {
+	for _, field := range m.Context {
+		if field.Type == "uuid" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// state поиск состояния модели по названию, либо nil
+func (m *Model) state(name string) *State {
+	for _, state := range m.States {
+		if state.Name == name {
+			return state
+		}
+	}
+
+	return nil
+}
+
+// StateNumber номер состояния в документации и превью (1., 2.), 0 – состояние не принадлежит модели
+func (m *Model) StateNumber(state *State) int {
+	for i, s := range m.States {
+		if s == state {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// FallbackState fallback failed state, созданный ParseModel для состояния (может быть nil)
+func (s *State) FallbackState() *State {
+	for _, transition := range s.Transitions {
+		if transition.Fallback {
+			return transition.State
+		}
+	}
+
+	return nil
+}
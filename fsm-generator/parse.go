@@ -4,28 +4,59 @@ import (
 	"bytes"
 	"crypto/md5" // #nosec
 	"encoding/hex"
-	"errors"
-	"fmt"
+	"io"
 	"log"
 	"os"
+	"reflect"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// ParseModel строго разбирает yaml модели: неизвестные поля и ошибки в переходах
+// возвращаются как Diagnostics с положением в файле
 func ParseModel(file *os.File) (*Model, error) {
-	d := yaml.NewDecoder(file)
-	d.KnownFields(false)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, yamlDiagnostics(file.Name(), err)
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, Diagnostics{{Pos: Position{File: file.Name()}, Msg: "empty model"}}
+	}
+
+	root := doc.Content[0]
+
+	ds := checkFields(file.Name(), root, reflect.TypeOf(Model{}))
+	if len(ds) > 0 {
+		return nil, ds
+	}
+
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true)
 
 	model := &Model{}
 
-	err := d.Decode(model)
+	err = d.Decode(model)
 	if err != nil {
-		return nil, err
+		return nil, yamlDiagnostics(file.Name(), err)
 	}
 
+	model.locate(file.Name(), root)
+
 	model.ETag = ETag(model)
 
+	if model.DefaultConfig == nil {
+		model.DefaultConfig = &ModelDefaultConfig{}
+	}
+
 	states := make([]*State, 0, len(model.States))
 
 	// разборка модели
@@ -42,9 +73,19 @@ func ParseModel(file *os.File) (*Model, error) {
 			state.CancellationTTL = model.DefaultConfig.CancellationTTL
 		}
 
+		if state.Backoff == nil && model.DefaultConfig.Backoff != nil {
+			backoff := *model.DefaultConfig.Backoff
+			state.Backoff = &backoff
+		}
+
+		if state.Backoff != nil && state.Backoff.Base == 0 {
+			state.Backoff.Base = state.MinRetryDelay
+		}
+
 		for _, transition := range state.Transitions {
 			if transition.StateName == state.Name {
-				return nil, errors.New("state can transit in itself")
+				ds.add(transition.src.at("to"), "state %s can transit in itself", state.Name)
+				continue
 			}
 
 			for _, otherState := range model.States {
@@ -55,8 +96,8 @@ func ParseModel(file *os.File) (*Model, error) {
 			}
 
 			if transition.State == nil {
-				// todo: дописать название состояний
-				return nil, fmt.Errorf("no state found for transition: model=%s state=%s", model.Title, state.Name)
+				ds.add(transition.src.at("to"), "no state %s found for transition from state %s",
+					transition.StateName, state.Name)
 			}
 		}
 
@@ -70,16 +111,23 @@ func ParseModel(file *os.File) (*Model, error) {
 				MaxRetryCount:   state.MaxRetryCount,
 				MinRetryDelay:   state.MinRetryDelay,
 				CancellationTTL: state.CancellationTTL,
+				Backoff:         state.Backoff,
+				src:             state.src,
 			}
 			states = append(states, fallbackState)
 			state.Transitions = append(state.Transitions, &Transition{
 				StateName: fallbackState.Name,
 				Condition: "Исчерпаны попытки исполнить событие состояния " + state.Name,
+				Fallback:  true,
 				State:     fallbackState,
 			})
 		}
 	}
 
+	if len(ds) > 0 {
+		return nil, ds
+	}
+
 	model.States = states
 
 	return model, nil
@@ -1,84 +1,98 @@
 package fsm_generator
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+)
 
-	"github.com/goccy/go-graphviz"
-	"github.com/goccy/go-graphviz/cgraph"
+// PreviewFormat формат превью графа модели
+type PreviewFormat string
+
+const (
+	// PreviewDOT описание графа на языке graphviz, dir/<model>.dot
+	PreviewDOT PreviewFormat = "dot"
+	// PreviewMermaid stateDiagram-v2, dir/<model>.mmd (отображается в markdown GitLab/GitHub)
+	PreviewMermaid PreviewFormat = "mermaid"
+	// PreviewSVG изображение, dir/<model>.svg (требует graphviz)
+	PreviewSVG PreviewFormat = "svg"
+	// PreviewPNG изображение, dir/<model>.png (требует graphviz)
+	PreviewPNG PreviewFormat = "png"
 )
 
-func (m *Model) MakePreview() error {
-	g := graphviz.New()
+// ErrGraphvizUnavailable генератор собран без graphviz (без cgo или с тегом static_build)
+// и утилита dot не найдена в PATH
+var ErrGraphvizUnavailable = errors.New("graphviz is not available: build with cgo or install dot, " +
+	"use dot or mermaid preview format")
 
-	graph, err := g.Graph()
-	if err != nil {
-		return err
+// Ext расширение файла превью
+func (f PreviewFormat) Ext() string {
+	if f == PreviewMermaid {
+		return ".mmd"
 	}
 
-	graph.
-		SetLabelLocation(cgraph.TopLocation).
-		SetLabel(m.Title + " (" + m.Name + " v." + m.ETag + ")").
-		SetPad(0.5).
-		SetRankSeparator(2).
-		SetNodeSeparator(1)
-
-	prefix := m.Prefix()
-
-	for _, state := range m.States {
-		var node *cgraph.Node
-
-		node, err = graph.CreateNode(state.Name)
-		if err != nil {
-			return err
-		}
-
-		node.SetLabel(prefix + state.Name).SetFontSize(28) // + "\n" + state.Description
-		node.SetShape(cgraph.RectShape).SetPenWidth(2.5).SetMargin(1)
+	return "." + string(f)
+}
 
-		if state.Initial {
-			node.SetRoot(true)
-		}
+// ParsePreviewFormats разбирает список форматов через запятую
+func ParsePreviewFormats(s string) ([]PreviewFormat, error) {
+	var formats []PreviewFormat
 
-		if state.SuccessFinal {
-			node.SetColor("#66cc00").SetFontColor("#66cc00")
-		}
+	for _, name := range strings.Split(s, ",") {
+		format := PreviewFormat(strings.TrimSpace(name))
 
-		if state.FailFinal {
-			node.SetColor("#ff937a").SetFontColor("#ff937a")
+		switch format {
+		case PreviewDOT, PreviewMermaid, PreviewSVG, PreviewPNG:
+			formats = append(formats, format)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown preview format %q", name)
 		}
-
-		state.GraphNode = node
 	}
 
-	for _, state := range m.States {
-		for _, transition := range state.Transitions {
-			A := state
-			B := transition.State
-
-			var e *cgraph.Edge
+	return formats, nil
+}
 
-			// todo: нумеровать все узлы (1., 2.) и ребра (1.1., 2.5), чтобы было удобно ссылаться в доке
-			e, err = graph.CreateEdge(A.Name+"->"+B.Name, A.GraphNode, B.GraphNode)
-			if err != nil {
-				return err
-			}
+// DefaultPreviewFormats форматы превью по умолчанию: png, если graphviz доступен, иначе dot и mermaid
+func DefaultPreviewFormats() []PreviewFormat {
+	if graphvizAvailable() {
+		return []PreviewFormat{PreviewPNG}
+	}
 
-			e.SetLabel(transition.Condition).
-				SetLabelPosition(5, 0).
-				SetColor("#888888").
-				SetArrowSize(2).
-				SetPenWidth(6)
+	return []PreviewFormat{PreviewDOT, PreviewMermaid}
+}
 
-			if B.FailFinal {
-				e.SetColor("#dedede")
-			}
-		}
+// MakePreview рисует граф модели в dir/<model>.<ext> в каждом из форматов (по умолчанию DefaultPreviewFormats)
+func (m *Model) MakePreview(dir string, formats ...PreviewFormat) error {
+	if len(formats) == 0 {
+		formats = DefaultPreviewFormats()
 	}
 
-	err = g.RenderFilename(graph, graphviz.PNG, filepath.Clean(filepath.Join(previewsDir, m.Name+".png")))
+	err := os.MkdirAll(dir, 0750)
 	if err != nil {
 		return err
 	}
 
+	for _, format := range formats {
+		path := filepath.Clean(filepath.Join(dir, m.Name+format.Ext()))
+
+		switch format {
+		case PreviewDOT:
+			err = os.WriteFile(path, m.DOT(), 0600)
+		case PreviewMermaid:
+			err = os.WriteFile(path, []byte(m.Mermaid()), 0600)
+		case PreviewSVG, PreviewPNG:
+			err = renderGraph(m.DOT(), format, path)
+		default:
+			err = fmt.Errorf("unknown preview format %q", format)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s model %s preview err: %w", m.Name, format, err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,64 @@
+package fsm_generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DOT описание графа модели на языке graphviz
+func (m *Model) DOT() []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(m.Name))
+	fmt.Fprintf(&b, "\tlabel=%s labelloc=t pad=0.5 ranksep=2 nodesep=1\n",
+		dotQuote(m.Title+" ("+m.Name+" v."+m.ETag+")"))
+	b.WriteString("\tnode [shape=rect penwidth=2.5 margin=1 fontsize=28]\n")
+	b.WriteString("\tedge [color=\"#888888\" arrowsize=2 penwidth=6]\n\n")
+
+	prefix := m.Prefix()
+
+	// состояния и переходы нумеруются так же, как в документации модели (1., 1.2.)
+	for i, state := range m.States {
+		attrs := []string{"label=" + dotQuote(fmt.Sprintf("%d. %s%s", i+1, prefix, state.Name))}
+
+		if state.Initial {
+			attrs = append(attrs, "root=true")
+		}
+
+		if state.SuccessFinal {
+			attrs = append(attrs, `color="#66cc00" fontcolor="#66cc00"`)
+		}
+
+		if state.FailFinal {
+			attrs = append(attrs, `color="#ff937a" fontcolor="#ff937a"`)
+		}
+
+		fmt.Fprintf(&b, "\t%s [%s]\n", dotQuote(state.Name), strings.Join(attrs, " "))
+	}
+
+	b.WriteString("\n")
+
+	for i, state := range m.States {
+		for j, transition := range state.Transitions {
+			attrs := []string{"label=" + dotQuote(fmt.Sprintf("%d.%d. %s", i+1, j+1, transition.Condition))}
+
+			if transition.State.FailFinal {
+				attrs = append(attrs, `color="#dedede"`)
+			}
+
+			fmt.Fprintf(&b, "\t%s -> %s [%s]\n",
+				dotQuote(state.Name), dotQuote(transition.State.Name), strings.Join(attrs, " "))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+func dotQuote(s string) string {
+	return `"` + dotReplacer.Replace(s) + `"`
+}
@@ -0,0 +1,27 @@
+//go:build cgo && !static_build
+// +build cgo,!static_build
+
+package fsm_generator
+
+import (
+	"github.com/goccy/go-graphviz"
+)
+
+func graphvizAvailable() bool {
+	return true
+}
+
+// renderGraph рисует граф средствами go-graphviz (cgo)
+func renderGraph(dot []byte, format PreviewFormat, path string) error {
+	graph, err := graphviz.ParseBytes(dot)
+	if err != nil {
+		return err
+	}
+
+	defer graph.Close()
+
+	g := graphviz.New()
+	defer g.Close()
+
+	return g.RenderFilename(graph, graphviz.Format(format), path)
+}
@@ -0,0 +1,57 @@
+package fsm_generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mermaid описание графа модели в виде mermaid stateDiagram-v2
+func (m *Model) Mermaid() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%%%% %s\n", mermaidText(m.Title+" ("+m.Name+" v."+m.ETag+")"))
+	b.WriteString("stateDiagram-v2\n")
+	b.WriteString("    classDef success color:#66cc00,stroke:#66cc00\n")
+	b.WriteString("    classDef fail color:#ff937a,stroke:#ff937a\n")
+
+	prefix := m.Prefix()
+
+	// состояния и переходы нумеруются так же, как в документации модели (1., 1.2.)
+	for i, state := range m.States {
+		fmt.Fprintf(&b, "    state \"%d. %s\" as %s\n", i+1, mermaidText(prefix+state.Name), state.Name)
+	}
+
+	for i, state := range m.States {
+		if state.Initial {
+			fmt.Fprintf(&b, "    [*] --> %s\n", state.Name)
+		}
+
+		for j, transition := range state.Transitions {
+			fmt.Fprintf(&b, "    %s --> %s: %d.%d. %s\n",
+				state.Name, transition.State.Name, i+1, j+1, mermaidText(transition.Condition))
+		}
+
+		if state.SuccessFinal || state.FailFinal {
+			fmt.Fprintf(&b, "    %s --> [*]\n", state.Name)
+		}
+	}
+
+	for _, state := range m.States {
+		if state.SuccessFinal {
+			fmt.Fprintf(&b, "    class %s success\n", state.Name)
+		}
+
+		if state.FailFinal {
+			fmt.Fprintf(&b, "    class %s fail\n", state.Name)
+		}
+	}
+
+	return b.String()
+}
+
+// mermaidReplacer экранирует символы, ломающие разбор подписей mermaid
+var mermaidReplacer = strings.NewReplacer("\r", "", "\n", " ", `"`, "#quot;", ":", "#58;", ";", "#59;")
+
+func mermaidText(s string) string {
+	return strings.TrimSpace(mermaidReplacer.Replace(s))
+}
@@ -0,0 +1,37 @@
+//go:build !cgo || static_build
+// +build !cgo static_build
+
+package fsm_generator
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+)
+
+func graphvizAvailable() bool {
+	_, err := exec.LookPath("dot")
+
+	return err == nil
+}
+
+// renderGraph рисует граф утилитой dot из PATH, go-graphviz требует cgo и не собирается статически
+func renderGraph(dot []byte, format PreviewFormat, path string) error {
+	bin, err := exec.LookPath("dot")
+	if err != nil {
+		return ErrGraphvizUnavailable
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(bin, "-T"+string(format), "-o", path) // #nosec G204
+	cmd.Stdin = bytes.NewReader(dot)
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("dot: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
@@ -5,10 +5,10 @@ import (
 	"embed"
 	"fmt"
 	"go/format"
-	"html/template"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/iancoleman/strcase"
@@ -37,7 +37,9 @@ func NewTemplateBuilder() (*TemplateBuilder, error) {
 		"camel":           func(name string) string { return strcase.ToCamel(strcase.ToSnake(name)) },
 		"lower":           strings.ToLower,
 		"upper":           strings.ToUpper,
-	}).ParseFS(templatesContent, templatesDir)
+		"inc":             func(i int) int { return i + 1 },
+		"cell":            markdownCell,
+	}).ParseFS(templatesContent, templatesDir, docsTemplatesDir)
 	if err != nil {
 		return nil, err
 	}
@@ -45,99 +47,165 @@ func NewTemplateBuilder() (*TemplateBuilder, error) {
 	return tb, nil
 }
 
-// GenerateModel генерирует файлы модели по указанному пути
-func (t *TemplateBuilder) GenerateModel(model *Model) error {
+// GeneratedFile файл пакета модели, сгенерированный в памяти
+type GeneratedFile struct {
+	// Path путь до файла
+	Path string
+	// Content отформатированный код
+	Content []byte
+	// Editable файл редактируется разработчиком (YOU SHOULD EDIT), создается, только если его еще нет
+	Editable bool
+}
+
+// ModelDir директория пакета модели
+func ModelDir(model *Model, outputDir string) string {
+	dir := model.Dir
+	if dir == "" {
+		dir = model.Package
+	}
+
+	return filepath.Join(outputDir, dir)
+}
+
+// RenderModel генерирует в памяти все файлы пакета модели outputDir/<Model.Package>
+func (t *TemplateBuilder) RenderModel(model *Model, outputDir string) ([]*GeneratedFile, error) {
 	tm := &TemplateModel{
 		Model: model,
 		State: nil,
 	}
 
-	// filepath путь до папки с fsm моделью
-	pwd, err := os.Getwd()
-	if err != nil {
-		return err
-	}
+	// fp путь до папки с fsm моделью
+	fp := ModelDir(model, outputDir)
 
-	fp := filepath.Join(pwd, outputFsmDir, strcase.ToSnake(model.Name))
+	var files []*GeneratedFile
 
-	err = os.Mkdir(fp, 0750)
-	if err != nil && !os.IsExist(err) {
-		return err
+	add := func(template string, name string, editable bool) error {
+		content, err := t.render(template, tm)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		files = append(files, &GeneratedFile{
+			Path:     filepath.Join(fp, name),
+			Content:  content,
+			Editable: editable,
+		})
+
+		return nil
 	}
 
 	// модель
-	modelFp := fp + "/" + model.Name + "_model.fsm.go"
-
-	err = t.GenerateFromTemplate("model.fsm.go.tpl", modelFp, tm)
+	err := add("model.fsm.go.tpl", model.Name+"_model.fsm.go", false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// интерфейс сервиса
-	svcFp := fp + "/service.fsm.go"
-
-	err = t.GenerateFromTemplate("service.fsm.go.tpl", svcFp, tm)
+	err = add("service.fsm.go.tpl", "service.fsm.go", true)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// контекстные данные модели
+	if len(model.Context) > 0 {
+		err = add("context.fsm.go.tpl", "context.fsm.go", false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// миграция транзакций из удаленных состояний
+	if model.Migration != nil {
+		err = add("migration.fsm.go.tpl", "migration.fsm.go", false)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	for _, state := range model.States {
 		tm.State = state
 
-		// состояние
-		stateFp := fp + "/" + strcase.ToSnake(state.Name) + ".fsm.go"
+		name := strcase.ToSnake(state.Name)
 
-		err = t.GenerateFromTemplate("state.fsm.go.tpl", stateFp, tm)
+		// состояние
+		err = add("state.fsm.go.tpl", name+".fsm.go", false)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// обработчик состояния
-		handlerFp := fp + "/" + strcase.ToSnake(state.Name) + ".handler.fsm.go"
-		if _, err = os.Stat(handlerFp); os.IsNotExist(err) {
-			err = t.GenerateFromTemplate("state.handler.fsm.go.tpl", handlerFp, tm)
+		err = add("state.handler.fsm.go.tpl", name+".handler.fsm.go", true)
+		if err != nil {
+			return nil, err
+		}
+
+		// компенсирующий обработчик состояния
+		if state.Compensation {
+			err = add("state.compensation.fsm.go.tpl", name+".compensation.fsm.go", true)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	return nil
+	return files, nil
 }
 
-func (t *TemplateBuilder) GenerateFromTemplate(template string, path string, tm *TemplateModel) error {
-	// open file
-	f, err := os.OpenFile(filepath.Clean(path), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755) //nolint: gosec
+// GenerateModel генерирует файлы модели в пакете outputDir/<Model.Package>,
+// редактируемые файлы (сервис, обработчики) создаются, только если их еще нет
+func (t *TemplateBuilder) GenerateModel(model *Model, outputDir string) error {
+	files, err := t.RenderModel(model, outputDir)
 	if err != nil {
 		return err
 	}
 
-	buf := new(bytes.Buffer)
-
-	// exec template
-	err = t.Templates.ExecuteTemplate(buf, template, tm)
+	err = os.MkdirAll(ModelDir(model, outputDir), 0750)
 	if err != nil {
 		return err
 	}
 
-	// apply go format
-	formatted, err := format.Source(buf.Bytes())
-	if err != nil {
-		return err
+	for _, file := range files {
+		if file.Editable {
+			_, err = os.Stat(file.Path)
+			if err == nil {
+				continue
+			}
+
+			if !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		err = os.WriteFile(filepath.Clean(file.Path), file.Content, 0755) //nolint: gosec
+		if err != nil {
+			return err
+		}
 	}
 
-	// write in file
-	_, err = f.Write(formatted)
+	return nil
+}
+
+func (t *TemplateBuilder) GenerateFromTemplate(template string, path string, tm *TemplateModel) error {
+	content, err := t.render(template, tm)
 	if err != nil {
 		return err
 	}
 
-	err = f.Close()
+	return os.WriteFile(filepath.Clean(path), content, 0755) //nolint: gosec
+}
+
+// render исполняет шаблон и форматирует результат
+func (t *TemplateBuilder) render(template string, tm *TemplateModel) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	// exec template
+	err := t.Templates.ExecuteTemplate(buf, template, tm)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	// apply go format
+	return format.Source(buf.Bytes())
 }
 
 func (m *TemplateModel) MinRetryDelayFormatted() string {
@@ -148,6 +216,23 @@ func (m *TemplateModel) CancellationTTLFormatted() string {
 	return m.formatDur(m.State.CancellationTTL)
 }
 
+// CircuitBreakerOpenTimeoutFormatted без комментария, так как используется внутри литерала структуры
+func (m *TemplateModel) CircuitBreakerOpenTimeoutFormatted() string {
+	return m.formatDurValue(m.State.CircuitBreaker.OpenTimeout)
+}
+
+func (m *TemplateModel) BackoffBaseFormatted() string {
+	return m.formatDurValue(m.State.Backoff.Base)
+}
+
+func (m *TemplateModel) BackoffMaxFormatted() string {
+	return m.formatDurValue(m.State.Backoff.Max)
+}
+
+func (m *TemplateModel) formatDurValue(d time.Duration) string {
+	return fmt.Sprintf("%d * time.Second", int(d.Seconds()))
+}
+
 func (m *TemplateModel) formatDur(d time.Duration) string {
 	return fmt.Sprintf("%d * time.Second // %s", int(d.Seconds()), d.String())
 }
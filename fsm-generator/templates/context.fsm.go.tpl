@@ -0,0 +1,30 @@
+{{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
+// Code generated by fsm-generator. DO NOT EDIT.
+package {{ .Model.Package }}
+
+import (
+    {{- if .Model.ContextUsesTime }}
+    "time"
+    {{ end }}
+    {{- if .Model.ContextUsesUUID }}
+    "github.com/google/uuid"
+    {{ end }}
+    "{{ .Model.Import "model" }}"
+)
+
+// ContextData контекстные данные модели, передаваемые между состояниями
+type ContextData struct {
+    {{- range $val := .Model.Context }}
+    {{- if $val.Description }}
+    // {{ $val.Name | camel }} {{ $val.Description }}
+    {{- end }}
+    {{ $val.Name | camel }} {{ $val.GoType }} `json:"{{ $val.Name }}"`
+    {{- end }}
+}
+
+// ContextDataOf контекстные данные модели из события, изменения сохраняются движком в транзакции
+func ContextDataOf(ev *model.Event) *ContextData {
+    data, _ := ev.Data.(*ContextData)
+
+    return data
+}
@@ -0,0 +1,20 @@
+{{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
+// Code generated by fsm-generator. DO NOT EDIT.
+package {{ .Model.Package }}
+
+import (
+    "{{ .Model.Import "migration" }}"
+)
+
+// Migration перевод транзакций из удаленных или переименованных состояний модели (Engine.Migrate)
+var Migration = &migration.Spec{
+    Model: "{{ .Model.Name | snake }}",
+    States: []*migration.StateMigration{
+    {{- range $val := .Model.Migration.States }}
+        {
+            From: "{{ $.Model.Prefix | upper }}{{ $val.From | screaming_snake }}",
+            To:   "{{ $.Model.Prefix | upper }}{{ $val.To | screaming_snake }}",
+        },
+    {{- end }}
+    },
+}
@@ -1,10 +1,10 @@
 {{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
 // Code generated by fsm-generator. DO NOT EDIT.
 // Model revision v.{{ .Model.ETag }}
-package {{ .Model.Name | snake }}
+package {{ .Model.Package }}
 
 import (
-  "fsm-framework/fsm-engine/model"
+  "{{ .Model.Import "model" }}"
 )
 
 var Model model.Model = &{{ .Model.Name | camel }}Model{}
@@ -18,6 +18,10 @@ func (m *{{ .Model.Name | camel }}Model) Name() string {
 	return "{{ .Model.Name | snake }}"
 }
 
+func (m *{{ .Model.Name | camel }}Model) Version() string {
+	return "{{ .Model.ETag }}"
+}
+
 func (m *{{ .Model.Name | camel }}Model) States() []model.State {
     return []model.State{
     {{- range $val := .Model.States}}
@@ -68,4 +72,12 @@ func (m *{{ .Model.Name | camel }}Model) SetService(svc interface{}) error {
 
 func (m *{{ .Model.Name | camel }}Model) Service() interface{} {
     return m.svc
+}
+
+func (m *{{ .Model.Name | camel }}Model) NewContextData() interface{} {
+    {{- if .Model.Context }}
+    return &ContextData{}
+    {{- else }}
+    return nil
+    {{- end }}
 }
\ No newline at end of file
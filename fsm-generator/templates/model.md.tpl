@@ -0,0 +1,34 @@
+{{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
+<!-- Code generated by fsm-generator. DO NOT EDIT. -->
+# {{ .Model.Title }} ({{ .Model.Name }} v.{{ .Model.ETag }})
+{{- with .Model.ImportPath }}
+
+Пакет: `{{ . }}`
+{{- end }}
+
+## Состояния
+
+| № | Состояние | Описание | Начальное | Конечное | Повторы | Задержка | TTL | Fallback |
+|---|-----------|----------|-----------|----------|---------|----------|-----|----------|
+{{- range $i, $s := .Model.States }}
+| {{ inc $i }}. | `{{ $.Model.Prefix }}{{ $s.Name }}` | {{ cell $s.Description }} | {{ if $s.Initial }}да{{ end }} | {{ if $s.SuccessFinal }}успешное{{ else if $s.FailFinal }}неудачное{{ end }} |
+{{- if or $s.SuccessFinal $s.FailFinal }} – | – | – |
+{{- else }} {{ $s.MaxRetryCount }} | {{ $s.MinRetryDelay }}{{ with $s.Backoff }}, {{ .Strategy }}{{ end }} | {{ $s.CancellationTTL }} |
+{{- end }} {{ with $s.FallbackState }}{{ $.Model.StateNumber . }}. `{{ .Name }}`{{ end }} |
+{{- end }}
+
+## Переходы
+
+| № | Из | В | Условие |
+|---|----|---|---------|
+{{- range $i, $s := .Model.States }}
+{{- range $j, $t := $s.Transitions }}
+| {{ inc $i }}.{{ inc $j }}. | {{ inc $i }}. `{{ $s.Name }}` | {{ $.Model.StateNumber $t.State }}. `{{ $t.State.Name }}` | {{ cell $t.Condition }}{{ if $t.Fallback }} (fallback){{ end }} |
+{{- end }}
+{{- end }}
+
+## Граф
+
+```mermaid
+{{ .Model.Mermaid -}}
+```
@@ -1,5 +1,5 @@
 {{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
-package {{ .Model.Name | snake }}
+package {{ .Model.Package }}
 
 // Code generated by fsm-generator. YOU SHOULD EDIT THIS FILE
 
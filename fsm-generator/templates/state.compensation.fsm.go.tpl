@@ -0,0 +1,14 @@
+{{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
+package {{ .Model.Package }}
+
+// Code generated by fsm-generator. YOU SHOULD EDIT THIS FILE
+
+import (
+    "context"
+
+    "{{ .Model.Import "model" }}"
+)
+
+func (s *{{ .State.Name | camel }}StateDeclaration) Compensate(ctx context.Context, ev *model.Event) error {
+    panic("implement {{ .Model.Name | snake }} model {{ .State.Name | snake }} state compensation handler")
+}
@@ -1,14 +1,18 @@
 {{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
 // Code generated by fsm-generator. DO NOT EDIT.
-package {{ .Model.Name | snake }}
+package {{ .Model.Package }}
 
 import (
     "time"
 
-    "fsm-framework/fsm-engine/model"
+    "{{ .Model.Import "model" }}"
 )
 
 var {{ .State.Name | camel }}State model.State = &{{ .State.Name | camel }}StateDeclaration{}
+{{- if .State.Compensation }}
+
+var _ model.Compensator = &{{ .State.Name | camel }}StateDeclaration{}
+{{- end }}
 
 type {{ .State.Name | camel }}StateDeclaration struct {
 }
@@ -57,6 +61,41 @@ func (s *{{ .State.Name | camel }}StateDeclaration) FallbackState() model.State
     {{- end}}
 }
 
+func (s *{{ .State.Name | camel }}StateDeclaration) RateLimit() *model.RateLimit {
+    {{- if .State.RateLimit }}
+    return &model.RateLimit{
+        Rate:  {{ .State.RateLimit.Rate }},
+        Burst: {{ .State.RateLimit.Burst }},
+    }
+    {{- else }}
+    return nil
+    {{- end }}
+}
+
+func (s *{{ .State.Name | camel }}StateDeclaration) Backoff() *model.Backoff {
+    {{- if .State.Backoff }}
+    return &model.Backoff{
+        Strategy: model.{{ .State.Backoff.StrategyConst }},
+        Base:     {{ .BackoffBaseFormatted }},
+        Max:      {{ .BackoffMaxFormatted }},
+        Jitter:   {{ .State.Backoff.Jitter }},
+    }
+    {{- else }}
+    return nil
+    {{- end }}
+}
+
+func (s *{{ .State.Name | camel }}StateDeclaration) CircuitBreaker() *model.CircuitBreaker {
+    {{- if .State.CircuitBreaker }}
+    return &model.CircuitBreaker{
+        FailureThreshold: {{ .State.CircuitBreaker.FailureThreshold }},
+        OpenTimeout:      {{ .CircuitBreakerOpenTimeoutFormatted }},
+    }
+    {{- else }}
+    return nil
+    {{- end }}
+}
+
 func (s *{{ .State.Name | camel }}StateDeclaration) IsInitial() bool {
     {{- if .State.Initial }}
     return true
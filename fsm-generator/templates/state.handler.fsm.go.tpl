@@ -1,12 +1,12 @@
 {{- /* gotype: morpheus/pkg/fsm-generator.TemplateModel */ -}}
-package {{ .Model.Name | snake }}
+package {{ .Model.Package }}
 
 // Code generated by fsm-generator. YOU SHOULD EDIT THIS FILE
 
 import (
     "context"
 
-    "fsm-framework/fsm-engine/model"
+    "{{ .Model.Import "model" }}"
 )
 
 func (s *{{ .State.Name | camel }}StateDeclaration) EventHandler(ctx context.Context, ev *model.Event) model.State {
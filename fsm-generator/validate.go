@@ -2,22 +2,31 @@ package fsm_generator
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/iancoleman/strcase"
 )
 
+// ValidateModel проверяет модель, все найденные ошибки возвращаются как Diagnostics с положением в yaml файле
 func (m *Model) ValidateModel() error {
+	var ds Diagnostics
+
 	if !strings.EqualFold(m.Name, strcase.ToSnake(m.Name)) {
-		return errors.New("model name should be in snake_case: " + strcase.ToSnake(m.Name))
+		ds.add(Position{File: m.src.pos.File}, "model name should be in snake_case: %s", strcase.ToSnake(m.Name))
 	}
 
 	var (
 		initStates []*State
 		hasFinals  bool
+		fallbacks  = make(map[*State]bool)
 	)
 
+	for _, state := range m.States {
+		fallbacks[state.FallbackState()] = true
+	}
+
 	for _, state := range m.States {
 		if state.Initial {
 			initStates = append(initStates, state)
@@ -27,25 +36,130 @@ func (m *Model) ValidateModel() error {
 			hasFinals = true
 		}
 
+		// настройки fallback состояний скопированы из исходного состояния и проверяются в нем
+		if fallbacks[state] {
+			continue
+		}
+
 		if state.MinRetryDelay != 0 && state.MinRetryDelay < time.Second {
-			return errors.New(state.Name + ": min retry delay should be times of 1 second (or be equal zero)")
+			ds.add(m.stateAt(state, "min_retry_delay"),
+				"%s: min retry delay should be times of 1 second (or be equal zero)", state.Name)
 		}
 
 		if state.CancellationTTL != 0 && state.CancellationTTL < time.Second {
-			return errors.New(state.Name + ": cancellation ttl should be times of 1 second (or be equal zero)")
+			ds.add(m.stateAt(state, "cancellation_ttl"),
+				"%s: cancellation ttl should be times of 1 second (or be equal zero)", state.Name)
+		}
+
+		if state.Compensation && (state.SuccessFinal || state.FailFinal) {
+			ds.add(state.src.at("compensation"), "%s: final state can't have compensation", state.Name)
+		}
+
+		if state.RateLimit != nil && (state.RateLimit.Rate <= 0 || state.RateLimit.Burst < 0) {
+			ds.add(state.src.at("rate_limit"),
+				"%s: rate limit should have positive rate and non-negative burst", state.Name)
+		}
+
+		if state.Backoff != nil {
+			err := state.Backoff.validate()
+			if err != nil {
+				ds.add(m.stateAt(state, "backoff"), "%s: %v", state.Name, err)
+			}
+		}
+
+		if state.CircuitBreaker != nil && (state.CircuitBreaker.FailureThreshold <= 0 ||
+			state.CircuitBreaker.OpenTimeout < time.Second) {
+			ds.add(state.src.at("circuit_breaker"), "%s: circuit breaker should have positive failure threshold "+
+				"and open timeout not less than 1 second", state.Name)
 		}
 	}
 
+	ds = append(ds, m.validateContext()...)
+
 	// есть начальные состояния (минимум 1)
 	if len(initStates) == 0 {
-		return errors.New("model should have at least one init state")
+		ds.add(m.src.at("states"), "model should have at least one init state")
 	}
 	// есть конечные состояния (минимум 1)
 	if !hasFinals {
-		return errors.New("model should have at least one final state")
+		ds.add(m.src.at("states"), "model should have at least one final state")
+	}
+
+	// из начального состояния можно попасть в любое другое состояние (полная проверка графа – Model.Check)
+	reachable := m.reachable()
+
+	for _, state := range m.States {
+		if len(initStates) > 0 && !reachable[state] {
+			ds.add(state.src.at("name"), "%s: state is unreachable from initial states", state.Name)
+		}
+	}
+
+	return ds.err()
+}
+
+// validateContext проверяет декларацию контекстных данных модели
+func (m *Model) validateContext() Diagnostics {
+	var ds Diagnostics
+
+	names := make(map[string]bool, len(m.Context))
+
+	for _, field := range m.Context {
+		if field.Name == "" || field.Name != strcase.ToSnake(field.Name) {
+			ds.add(field.src.at("name"), "context field name should be in snake_case: %s", field.Name)
+		}
+
+		if names[field.Name] {
+			ds.add(field.src.at("name"), "context field declared more than once: %s", field.Name)
+		}
+
+		names[field.Name] = true
+
+		if field.GoType() == "" {
+			ds.add(field.src.at("type"), "context field %s has unsupported type %s", field.Name, field.Type)
+		}
+	}
+
+	return ds
+}
+
+// ValidateMigration проверяет миграцию транзакций относительно текущего состояния модели
+func (m *Model) ValidateMigration() error {
+	err := m.Migration.Validate()
+	if err != nil {
+		return err
+	}
+
+	if m.Migration.Model != "" && m.Migration.Model != m.Name {
+		return fmt.Errorf("migration declared for model %s", m.Migration.Model)
+	}
+
+	m.Migration.Model = m.Name
+
+	for _, sm := range m.Migration.States {
+		if m.state(sm.From) != nil {
+			return fmt.Errorf("migration state %s still exists in model", sm.From)
+		}
+
+		if m.state(sm.To) == nil {
+			return fmt.Errorf("migration state %s not found in model", sm.To)
+		}
 	}
 
-	// todo: из начального состояния можно попасть в любое другое состояние (покрасить граф)
+	return nil
+}
+
+func (b *Backoff) validate() error {
+	if _, ok := backoffStrategies[b.Strategy]; !ok {
+		return fmt.Errorf("backoff strategy %q is not supported", b.Strategy)
+	}
+
+	if b.Base < time.Second {
+		return errors.New("backoff base should be times of 1 second")
+	}
+
+	if b.Max != 0 && b.Max < b.Base {
+		return errors.New("backoff max should not be less than base")
+	}
 
 	return nil
 }